@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	logrepo "ndb/server/repositories/log"
+)
+
+// auditPageSize is the number of events read at once when the export is not limited.
+const auditPageSize = 500
+
+// runAudit exports audit events of an entity, of an actor or of the actor on the entity, the newest first.
+func runAudit(args []string) error {
+	var entityID, actorID, outputFile string
+	var limit int
+
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	flags.StringVar(&entityID, "entity", "", "ID of the thread, post or comment")
+	flags.StringVar(&actorID, "actor", "", "ID of the user who made the changes, system for background workers")
+	flags.IntVar(&limit, "limit", 0, "Maximum number of events, 0 exports all of them")
+	flags.StringVar(&outputFile, "output",
+		fmt.Sprintf("audit_%s.csv", strings.Replace(time.Now().Format(time.DateTime), " ", "_", 1)),
+		"Output CSV file",
+	)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if entityID == "" && actorID == "" {
+		flags.Usage()
+		return errors.New("entity or actor is required")
+	}
+
+	events, err := queryAudit(entityID, actorID, limit)
+	if err != nil {
+		return err
+	}
+
+	return writeAuditCSV(outputFile, events)
+}
+
+// queryAudit retrieves audit events from ScyllaDB with the same queries the server uses, page by page.
+func queryAudit(entityID, actorID string, limit int) ([]*logrepo.AuditEvent, error) {
+	ctx := context.Background()
+	store, err := logrepo.NewStore(
+		ctx,
+		&logrepo.ConnConfig{Consistency: gocql.Quorum, Keyspace: "log_storage", Hosts: []string{"127.0.0.1"}},
+		logrepo.WithLogger(slog.Default()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer store.Close(ctx)
+
+	query := &logrepo.AuditQuery{EntityID: entityID, ActorID: actorID, Limit: auditPageSize}
+	if limit > 0 && limit < auditPageSize {
+		query.Limit = limit
+	}
+
+	var events []*logrepo.AuditEvent
+	for {
+		page, pageState, err := store.ListAuditEvents(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query audit events: %w", err)
+		}
+		events = append(events, page...)
+
+		if limit > 0 && len(events) >= limit {
+			return events[:limit], nil
+		}
+		if pageState == nil {
+			return events, nil
+		}
+		query.PageState = pageState
+	}
+}
+
+// writeAuditCSV writes the audit events to a CSV file.
+func writeAuditCSV(fileName string, events []*logrepo.AuditEvent) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err = writer.Write([]string{
+		"Time", "Entity Type", "Entity ID", "Action", "Actor ID", "Actor Name", "Request ID", "Before", "After",
+	}); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	for _, event := range events {
+		if err = writer.Write([]string{
+			event.OccurredAt().Format(time.RFC3339),
+			event.EntityType,
+			event.EntityID,
+			event.Action,
+			event.ActorID,
+			event.ActorName,
+			event.RequestID,
+			fmt.Sprintf("%v", event.Before),
+			fmt.Sprintf("%v", event.After),
+		}); err != nil {
+			return fmt.Errorf("failed to write audit event: %w", err)
+		}
+	}
+
+	return nil
+}
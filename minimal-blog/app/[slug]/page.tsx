@@ -3,14 +3,19 @@ import {ArrowLeftIcon} from "@heroicons/react/24/solid"
 import {remark} from 'remark';
 import remarkGfm from 'remark-gfm'
 import html from 'remark-html';
-import {getArticleData, getMarkdownContent} from "@/lib/articles";
+import {fetchRelatedPosts, getArticleData, getMarkdownContent} from "@/lib/articles";
+import ReadNext from "@/components/ReadNext";
+import type {Author} from "@/types";
 
 const Article = async ({params}: { params: { slug: string } }) => {
     let articleData;
 
     try {
         articleData = await getArticleData(params.slug);
-        const markdownContent = await getMarkdownContent(articleData.content_file);
+        const [markdownContent, related] = await Promise.all([
+            getMarkdownContent(articleData.content_file),
+            fetchRelatedPosts(params.slug),
+        ]);
 
         console.log(articleData)
         // Convert markdown to HTML
@@ -30,10 +35,18 @@ const Article = async ({params}: { params: { slug: string } }) => {
                     </Link>
                     <p>{new Date(articleData.date).toLocaleDateString()}</p>
                 </div>
+                {articleData.authors.length > 0 && (
+                    <p className="font-poppins text-neutral-600">
+                        By {articleData.authors
+                            .map((author: Author) => author.display_name || author.username || author.user_id)
+                            .join(", ")}
+                    </p>
+                )}
                 <article
                     className="article"
                     dangerouslySetInnerHTML={{__html: contentHtml}}
                 />
+                <ReadNext related={related}/>
             </section>
         );
     } catch (error) {
@@ -1,11 +1,21 @@
 import ArticleItemList from "@/components/ArticleListItem"
+import TagCloud from "@/components/TagCloud"
+import TopTags from "@/components/TopTags"
 import {fetchPosts}  from "@/lib/articles"
+import {fetchTagUsage} from "@/lib/tags"
 import {PostItem} from "@/types";
 
 // This is a server component, and you can use async functions directly
 const HomePage = async () => {
     try {
-        const posts = await fetchPosts();
+        const [posts, popularTags, trendingTags] = await Promise.all([
+            fetchPosts(),
+            fetchTagUsage("popular", 50),
+            fetchTagUsage("trending", 5),
+        ]);
+
+        // The cloud shows the most popular tags in alphabetical order
+        const cloudTags = [...popularTags].sort((a, b) => a.display_name.localeCompare(b.display_name));
 
         // Group the posts by thread name
         const groupedPosts: Record<string, PostItem[]> = posts.reduce((acc, post) => {
@@ -30,6 +40,13 @@ const HomePage = async () => {
                         />
                     ))}
                 </section>
+                <section className="md:grid md:grid-cols-2 flex flex-col gap-10">
+                    <TagCloud tags={cloudTags}/>
+                    <div className="flex flex-col gap-10">
+                        <TopTags title="Top tags" tags={popularTags.slice(0, 5)}/>
+                        <TopTags title="Trending" tags={trendingTags}/>
+                    </div>
+                </section>
             </section>
         );
     } catch (error) {
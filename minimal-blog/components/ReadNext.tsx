@@ -0,0 +1,47 @@
+import Link from "next/link"
+import type { RelatedPost } from "@/types"
+
+interface Props {
+  related: RelatedPost[]
+}
+
+const reasonLabel = (hit: RelatedPost) => {
+  switch (hit.reasons[0]) {
+    case "same_author":
+      return "Same author"
+    case "shared_tags":
+      return hit.shared_tags.map((tag) => `#${tag}`).join(" ")
+    case "same_thread":
+      return hit.post.thread
+    case "co_read":
+      return "Readers also read"
+    default:
+      return ""
+  }
+}
+
+const ReadNext = ({ related }: Props) => {
+  if (related.length === 0) {
+    return null
+  }
+
+  return (
+    <div className="flex flex-col gap-5 mt-10 border-t border-neutral-200 pt-10">
+      <h2 className="font-cormorantGaramond text-4xl">Read next</h2>
+      <div className="flex flex-col gap-2.5 font-poppins text-lg">
+        {related.map((hit) => (
+          <Link
+            href={`/${hit.post.post_id}`}
+            key={hit.post.post_id}
+            className="flex justify-between gap-5 text-neutral-900 hover:text-amber-700 transition duration-150"
+          >
+            <span>{hit.post.title}</span>
+            <span className="text-sm text-neutral-500">{reasonLabel(hit)}</span>
+          </Link>
+        ))}
+      </div>
+    </div>
+  )
+}
+
+export default ReadNext
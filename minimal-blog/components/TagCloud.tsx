@@ -0,0 +1,33 @@
+import type { TagUsage } from "@/types"
+
+interface Props {
+  tags: TagUsage[]
+}
+
+// Font sizes range from 0.875rem for the least used tags to 2rem for the most used one
+const fontSize = (weight: number) => `${0.875 + weight * 1.125}rem`
+
+const TagCloud = ({ tags }: Props) => {
+  if (tags.length === 0) {
+    return null
+  }
+
+  return (
+    <div className="flex flex-col gap-5">
+      <h2 className="font-cormorantGaramond text-4xl">Tags</h2>
+      <div className="flex flex-wrap items-baseline gap-x-4 gap-y-2 font-poppins text-neutral-900">
+        {tags.map((tag) => (
+          <span
+            key={tag.name}
+            style={{ fontSize: fontSize(tag.weight) }}
+            title={`${tag.posts} posts, ${tag.threads} threads, ${tag.views} views`}
+          >
+            {tag.display_name}
+          </span>
+        ))}
+      </div>
+    </div>
+  )
+}
+
+export default TagCloud
@@ -0,0 +1,28 @@
+import type { TagUsage } from "@/types"
+
+interface Props {
+  title: string
+  tags: TagUsage[]
+}
+
+const TopTags = ({ title, tags }: Props) => {
+  if (tags.length === 0) {
+    return null
+  }
+
+  return (
+    <div className="flex flex-col gap-5">
+      <h2 className="font-cormorantGaramond text-4xl">{title}</h2>
+      <ol className="flex flex-col gap-2.5 font-poppins text-lg">
+        {tags.map((tag) => (
+          <li key={tag.name} className="flex justify-between gap-5 text-neutral-900">
+            <span>{tag.display_name}</span>
+            <span className="text-sm text-neutral-500">{tag.posts} posts</span>
+          </li>
+        ))}
+      </ol>
+    </div>
+  )
+}
+
+export default TopTags
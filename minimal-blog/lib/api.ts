@@ -0,0 +1,8 @@
+import { headers } from "next/headers"
+
+// Pages are rendered on the server, so the API sees its address instead of the reader's. Forwarding the
+// reader's address keeps rate limits per reader, the API trusts it from HTTP_SERVER_TRUSTED_PROXIES only.
+export function forwardedHeaders(): HeadersInit {
+  const forwardedFor = headers().get("x-forwarded-for")
+  return forwardedFor ? { "X-Forwarded-For": forwardedFor } : {}
+}
@@ -1,7 +1,8 @@
-import type { PostItem } from "@/types"
+import type { PostItem, RelatedPost } from "@/types"
+import { forwardedHeaders } from "@/lib/api"
 export async function fetchPosts(): Promise<PostItem[]> {
   try {
-    const response = await fetch('http://localhost:8080/api/v1/posts?limit=4', { cache: 'no-store' }); // Replace with your API endpoint URL
+    const response = await fetch('http://localhost:8080/api/v1/posts?limit=12', { cache: 'no-store', headers: forwardedHeaders() }); // Replace with your API endpoint URL
     if (!response.ok) {
       throw new Error(`HTTP error! Status: ${response.status}`);
     }
@@ -10,22 +11,16 @@ export async function fetchPosts(): Promise<PostItem[]> {
 
     const posts: PostItem[] = [];
 
-    for (const threadName in data) {
-      if (data.hasOwnProperty(threadName)) {
-        const postsInThread = data[threadName];
-
-        for (const post of postsInThread) {
-          posts.push({
-            post_id: post.post_id,
-            user_id: post.user_id,
-            title: post.title,
-            date: post.date,
-            thread: threadName,
-            view_count: post.view_count.toString(),
-            content_file: post.content_file,
-          });
-        }
-      }
+    for (const post of data.posts) {
+      posts.push({
+        post_id: post.post_id,
+        authors: post.authors,
+        title: post.title,
+        date: post.date,
+        thread: post.thread_name,
+        view_count: post.view_count.toString(),
+        content_file: post.content_file,
+      });
     }
 
     return posts;
@@ -37,7 +32,7 @@ export async function fetchPosts(): Promise<PostItem[]> {
 
 export async function getArticleData(slug: string) {
   // Fetch the article metadata, including content_file
-  const res = await fetch(`http://localhost:8080/api/v1/posts/${slug}`, { cache: 'no-store' });
+  const res = await fetch(`http://localhost:8080/api/v1/posts/${slug}`, { cache: 'no-store', headers: forwardedHeaders() });
 
   if (!res.ok) {
     throw new Error('Failed to fetch article data');
@@ -46,7 +41,7 @@ export async function getArticleData(slug: string) {
   const resp = await res.json();
   return {
     post_id: resp.post_id,
-    user_id: resp.user_id,
+    authors: resp.authors,
     title: resp.title,
     date: resp.date,
     view_count: resp.view_count.toString(),
@@ -54,9 +49,44 @@ export async function getArticleData(slug: string) {
   }
 }
 
+export async function fetchRelatedPosts(slug: string): Promise<RelatedPost[]> {
+  // Related posts are optional, the article is shown without them when they fail to load
+  try {
+    const res = await fetch(`http://localhost:8080/api/v1/posts/${slug}/related?limit=3`, { cache: 'no-store', headers: forwardedHeaders() });
+    if (!res.ok) {
+      throw new Error(`HTTP error! Status: ${res.status}`);
+    }
+
+    const data = await res.json();
+
+    const related: RelatedPost[] = [];
+
+    for (const hit of data ?? []) {
+      related.push({
+        post: {
+          post_id: hit.post.post_id,
+          authors: hit.post.authors,
+          title: hit.post.title,
+          date: hit.post.date,
+          thread: hit.post.thread_name,
+          view_count: hit.post.view_count.toString(),
+          content_file: hit.post.content_file,
+        },
+        reasons: hit.reasons ?? [],
+        shared_tags: hit.shared_tags ?? [],
+      });
+    }
+
+    return related;
+  } catch (error) {
+    console.error('Error fetching related posts:', error);
+    return [];
+  }
+}
+
 export async function getMarkdownContent(contentFile: string) {
   // Fetch the markdown content for the article
-  const res = await fetch(`http://localhost:8080/api/v1/files/${contentFile}`, { cache: 'no-store' });
+  const res = await fetch(`http://localhost:8080/api/v1/files/${contentFile}`, { cache: 'no-store', headers: forwardedHeaders() });
 
   if (!res.ok) {
     throw new Error('Failed to fetch article content');
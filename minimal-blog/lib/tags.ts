@@ -0,0 +1,36 @@
+import type { TagUsage } from "@/types"
+import { forwardedHeaders } from "@/lib/api"
+
+export async function fetchTagUsage(sort: "popular" | "name" | "trending", limit: number): Promise<TagUsage[]> {
+  // Tags decorate the page, it is shown without them when they fail to load
+  try {
+    const response = await fetch(
+      `http://localhost:8080/api/v1/tag-usage?sort=${sort}&limit=${limit}`,
+      { cache: 'no-store', headers: forwardedHeaders() },
+    );
+    if (!response.ok) {
+      throw new Error(`HTTP error! Status: ${response.status}`);
+    }
+
+    const data = await response.json();
+
+    const tags: TagUsage[] = [];
+
+    for (const tag of data ?? []) {
+      tags.push({
+        name: tag.name,
+        display_name: tag.display_name || tag.name,
+        threads: tag.threads,
+        posts: tag.posts,
+        views: tag.views,
+        trend: tag.trend,
+        weight: tag.weight,
+      });
+    }
+
+    return tags;
+  } catch (error) {
+    console.error('Error fetching tags:', error);
+    return [];
+  }
+}
@@ -1,9 +1,32 @@
+export type Author = {
+  user_id: string
+  username?: string
+  display_name?: string
+  role: string
+}
+
 export type PostItem = {
   post_id: string
-  user_id: string
+  authors: Author[]
   title: string
   date: string
   thread: string
   view_count: string
   content_file: string
 }
+
+export type RelatedPost = {
+  post: PostItem
+  reasons: string[]
+  shared_tags: string[]
+}
+
+export type TagUsage = {
+  name: string
+  display_name: string
+  threads: number
+  posts: number
+  views: number
+  trend: number
+  weight: number
+}
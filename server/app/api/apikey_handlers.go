@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+)
+
+// CreateAPIKeyHandler handles the creation of an API key.
+//
+// @Summary Create an API key
+// @Description Issue a long-lived key for automated clients of the logged in user. The key is sent as "Bearer <key>"
+// and allows only what both the scopes of the key and the role of its owner allow. It is returned only once.
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param data body models.CreateAPIKeyRequest true "API key creation request"
+// @Security BearerAuth
+// @Success 200 {object} models.APIKeyCreationResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/apikeys [post]
+func (s *Server) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.CreateAPIKeyRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while creating API key", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	keyID, key, err := s.authService.CreateAPIKey(ctx, data)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error creating API key")
+		return
+	}
+
+	render.Render(w, r, &models.APIKeyCreationResponse{
+		Status: http.StatusOK,
+		KeyID:  keyID,
+		Key:    key,
+	})
+}
+
+// ListAPIKeysHandler handles listing API keys of the user.
+//
+// @Summary List own API keys
+// @Description Fetch API keys of the logged in user, including revoked and expired ones, the newest first.
+// @Tags apikeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKey "API keys"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/apikeys [get]
+func (s *Server) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	s.listAPIKeys(w, r, false)
+}
+
+// ListAllAPIKeysHandler handles listing API keys of all users.
+//
+// @Summary List all API keys
+// @Description Fetch API keys of every user, the newest first. Only admins can list them.
+// @Tags apikeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKey "API keys"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/admin/apikeys [get]
+func (s *Server) ListAllAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	s.listAPIKeys(w, r, true)
+}
+
+func (s *Server) listAPIKeys(w http.ResponseWriter, r *http.Request, all bool) {
+	keys, err := s.authService.ListAPIKeys(r.Context(), all)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error listing API keys")
+		return
+	}
+
+	render.Respond(w, r, keys)
+}
+
+// RevokeAPIKeyHandler handles revoking an API key.
+//
+// @Summary Revoke an API key
+// @Description Stop the key from working. Users revoke their own keys, admins any key.
+// @Tags apikeys
+// @Param id path string true "API key ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Active API key not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/apikeys/{id} [delete]
+func (s *Server) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	s.revokeAPIKey(w, r, false)
+}
+
+// RevokeAnyAPIKeyHandler handles revoking an API key of any user.
+//
+// @Summary Revoke an API key of any user
+// @Description Stop the key of any user from working. Only admins can revoke them.
+// @Tags apikeys
+// @Param id path string true "API key ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Active API key not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/admin/apikeys/{id} [delete]
+func (s *Server) RevokeAnyAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	s.revokeAPIKey(w, r, true)
+}
+
+func (s *Server) revokeAPIKey(w http.ResponseWriter, r *http.Request, asAdmin bool) {
+	keyID := r.PathValue("id")
+
+	if keyID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "key_id is empty",
+		})
+		return
+	}
+
+	if err := s.authService.RevokeAPIKey(r.Context(), keyID, asAdmin); err != nil {
+		s.renderServiceError(w, r, err, "Error revoking API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	apierr "ndb/server/errors"
+)
+
+// ListAuditEventsHandler handles querying the audit trail.
+//
+// @Summary List audit events
+// @Description Fetch a page of changes made to an entity, by an actor or by the actor to the entity, the newest first.
+// @Description Pass next_cursor of the previous page as after to get the next one. Only admins can read the audit trail.
+// @Tags admin
+// @Produce json
+// @Param entity_id query string false "ID of the thread, post or comment"
+// @Param actor_id query string false "ID of the user who made the changes, system for background workers"
+// @Param limit query int false "Number of events per page, 10 by default, up to 100"
+// @Param after query string false "Cursor returned as next_cursor of the previous page"
+// @Security BearerAuth
+// @Success 200 {object} models.AuditPage "Page of audit events"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/admin/audit [get]
+func (s *Server) ListAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse page parameters", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	page, err := s.auditService.List(ctx, query.Get("entity_id"), query.Get("actor_id"), params.Limit, params.After)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error listing audit events")
+		return
+	}
+
+	render.Render(w, r, page)
+}
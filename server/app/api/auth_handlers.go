@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+	"ndb/server/services/auth"
+)
+
+// LoginHandler handles the login of a user.
+//
+// @Summary Log in
+// @Description Check the username and password and issue an access token and a refresh token.
+// Send the access token in the Authorization header as "Bearer <token>".
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.LoginRequest true "Login request"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Invalid username or password"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/auth/login [post]
+func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.LoginRequest{}
+	if err = json.Unmarshal(b, data); err != nil || data.Username == "" || data.Password == "" {
+		s.log.ErrorContext(ctx, "Failed to parse login request", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	tokens, err := s.authService.Login(ctx, data.Username, data.Password)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error logging in")
+		return
+	}
+
+	render.Render(w, r, toTokenResponse(tokens))
+}
+
+// RefreshTokenHandler handles the exchange of a refresh token.
+//
+// @Summary Refresh tokens
+// @Description Exchange the refresh token for a new access token and refresh token. The refresh token can be used only once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.RefreshTokenRequest true "Refresh request"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Invalid or expired refresh token"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/auth/refresh [post]
+func (s *Server) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.RefreshTokenRequest{}
+	if err = json.Unmarshal(b, data); err != nil || data.RefreshToken == "" {
+		s.log.ErrorContext(ctx, "Failed to parse refresh request", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	tokens, err := s.authService.Refresh(ctx, data.RefreshToken)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error refreshing tokens")
+		return
+	}
+
+	render.Render(w, r, toTokenResponse(tokens))
+}
+
+// LogoutHandler handles the logout of a user.
+//
+// @Summary Log out
+// @Description Revoke the access token of the request and the refresh token issued with it.
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/auth/logout [post]
+func (s *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	token, _ := bearerToken(r.Header.Get("Authorization"))
+
+	if err := s.authService.Logout(r.Context(), token); err != nil {
+		s.renderServiceError(w, r, err, "Error logging out")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// OIDCLoginHandler handles the start of a login through the OpenID Connect provider.
+//
+// @Summary Log in with OIDC
+// @Description Redirect to the login page of the OpenID Connect provider, using the authorization code flow with PKCE.
+// The provider redirects back to the configured redirect URL with code and state, which are passed to /api/v1/auth/oidc/callback.
+// @Tags auth
+// @Success 302
+// @Failure 404 {object} errors.ErrResponse "OIDC login is not configured"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/auth/oidc/login [get]
+func (s *Server) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	url, err := s.authService.StartOIDCLogin(r.Context())
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error starting OIDC login")
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// OIDCCallbackHandler handles the end of a login through the OpenID Connect provider.
+//
+// @Summary Finish OIDC login
+// @Description Exchange the authorization code for an ID token, issue an access token and a refresh token and
+// redirect to the configured frontend page. The tokens are passed in the URL fragment as access_token,
+// refresh_token, token_type and expires_in, so they are not sent to any server. Failed logins redirect to
+// the same page with error and error_description in the fragment.
+// Users are created on their first login, their role follows the configured role claim.
+// @Tags auth
+// @Param code query string true "Authorization code returned by the provider"
+// @Param state query string true "State returned by the provider"
+// @Success 302
+// @Failure 404 {object} errors.ErrResponse "OIDC login is not configured"
+// @Router /api/v1/auth/oidc/callback [get]
+func (s *Server) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if s.oidcFrontendURL == "" {
+		s.renderServiceError(w, r, auth.ErrOIDCDisabled, "Error finishing OIDC login")
+		return
+	}
+
+	query := r.URL.Query()
+
+	// The provider redirects with an error when the user denies access or the login fails
+	if providerErr := query.Get("error"); providerErr != "" {
+		s.redirectToFrontend(w, r, url.Values{
+			"error":             {providerErr},
+			"error_description": {query.Get("error_description")},
+		})
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		s.redirectToFrontend(w, r, url.Values{
+			"error":             {"invalid_request"},
+			"error_description": {"code and state are required"},
+		})
+		return
+	}
+
+	tokens, err := s.authService.FinishOIDCLogin(r.Context(), state, code)
+	if err != nil {
+		s.log.ErrorContext(r.Context(), "Error finishing OIDC login", slog.Any("error", err))
+
+		fragment := url.Values{"error": {"access_denied"}, "error_description": {err.Error()}}
+		if serviceErrorStatus(err) == http.StatusInternalServerError {
+			fragment = url.Values{"error": {"server_error"}, "error_description": {"internal server error"}}
+		}
+		s.redirectToFrontend(w, r, fragment)
+		return
+	}
+
+	s.redirectToFrontend(w, r, url.Values{
+		"access_token":  {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+		"token_type":    {"Bearer"},
+		"expires_in":    {strconv.Itoa(int(tokens.ExpiresIn.Seconds()))},
+	})
+}
+
+// redirectToFrontend ends the OIDC login on the frontend page, passing the values in the URL fragment.
+func (s *Server) redirectToFrontend(w http.ResponseWriter, r *http.Request, fragment url.Values) {
+	target, _, _ := strings.Cut(s.oidcFrontendURL, "#")
+	http.Redirect(w, r, target+"#"+fragment.Encode(), http.StatusFound)
+}
+
+func toTokenResponse(tokens *auth.Tokens) *models.TokenResponse {
+	return &models.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+	}
+}
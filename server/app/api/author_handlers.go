@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+)
+
+// InviteAuthorHandler handles inviting a co-author to a post.
+//
+// @Summary Invite a co-author
+// @Description Invite the user to become a co-author of the post. The user becomes an author once they accept the
+// @Description invitation. Authors and editors of the post change it, contributors are credited only.
+// @Description Authors of the post invite co-authors, editors and admins invite them to any post.
+// @Tags authors
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param data body models.InviteAuthorRequest true "Invitation request"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Post or user not found"
+// @Failure 409 {object} errors.ErrResponse "User is already an author"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/authors [post]
+func (s *Server) InviteAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.InviteAuthorRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while inviting author", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	if err = s.postService.InviteAuthor(ctx, postID, data); err != nil {
+		s.renderServiceError(w, r, err, "Error inviting author")
+		return
+	}
+
+	render.Render(w, r, &models.PostUpdateResponse{
+		Status: http.StatusOK,
+		PostID: postID,
+	})
+}
+
+// AcceptInvitationHandler handles accepting an invitation to co-author a post.
+//
+// @Summary Accept a co-author invitation
+// @Description Become a co-author of the post with the role from the invitation.
+// @Tags authors
+// @Produce json
+// @Param id path string true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 404 {object} errors.ErrResponse "Invitation not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/invitation/accept [post]
+func (s *Server) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	if err := s.postService.AcceptInvitation(ctx, postID); err != nil {
+		s.renderServiceError(w, r, err, "Error accepting invitation")
+		return
+	}
+
+	render.Render(w, r, &models.PostUpdateResponse{
+		Status: http.StatusOK,
+		PostID: postID,
+	})
+}
+
+// DeclineInvitationHandler handles declining an invitation to co-author a post.
+//
+// @Summary Decline a co-author invitation
+// @Description Remove the invitation of the authenticated user to the post.
+// @Tags authors
+// @Produce json
+// @Param id path string true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 404 {object} errors.ErrResponse "Invitation not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/invitation/decline [post]
+func (s *Server) DeclineInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	if err := s.postService.DeclineInvitation(ctx, postID); err != nil {
+		s.renderServiceError(w, r, err, "Error declining invitation")
+		return
+	}
+
+	render.Render(w, r, &models.PostUpdateResponse{
+		Status: http.StatusOK,
+		PostID: postID,
+	})
+}
+
+// ListInvitationsHandler fetches pending co-author invitations of the authenticated user.
+//
+// @Summary List co-author invitations
+// @Description Fetch invitations of the authenticated user to co-author posts, the newest first.
+// @Tags authors
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Invitation "Pending invitations"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/invitations [get]
+func (s *Server) ListInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	invitations, err := s.postService.ListInvitations(ctx)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch invitations")
+		return
+	}
+
+	render.Respond(w, r, invitations)
+}
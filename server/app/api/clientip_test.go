@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"127.0.0.1", "::1", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("parseTrustedProxies() error = %v", err)
+	}
+	s := &Server{trustedProxies: proxies}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:51234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:         "untrusted client sending the header",
+			remoteAddr:   "203.0.113.7:51234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "trusted proxy",
+			remoteAddr:   "127.0.0.1:40000",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "chain of trusted proxies",
+			remoteAddr:   "[::1]:40000",
+			forwardedFor: []string{"198.51.100.1, 10.1.2.3"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "spoofed addresses before the client",
+			remoteAddr:   "127.0.0.1:40000",
+			forwardedFor: []string{"192.0.2.1, 198.51.100.1", "10.1.2.3"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:       "trusted proxy without the header",
+			remoteAddr: "127.0.0.1:40000",
+			want:       "127.0.0.1",
+		},
+		{
+			name:         "only trusted proxies",
+			remoteAddr:   "127.0.0.1:40000",
+			forwardedFor: []string{"10.0.0.2, 10.0.0.1"},
+			want:         "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/posts", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := s.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	for _, proxy := range []string{"localhost", "10.0.0.0/33", "300.1.1.1"} {
+		if _, err := parseTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("parseTrustedProxies(%q) error = nil, want error", proxy)
+		}
+	}
+}
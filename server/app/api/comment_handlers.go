@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+)
+
+// CreateCommentHandler handles adding a comment to a post.
+//
+// @Summary Comment on a post
+// @Description Add a comment to a published post, or a reply to another comment when parent_id is set.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param data body models.CreateCommentRequest true "Comment creation request"
+// @Security BearerAuth
+// @Success 200 {object} models.CommentCreationResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Post or parent comment not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/comments [post]
+func (s *Server) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.CreateCommentRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while creating comment", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+	data.UserID = principal(r).UserID
+
+	commentID, err := s.postService.CreateComment(ctx, postID, data)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error creating comment")
+		return
+	}
+
+	render.Render(w, r, &models.CommentCreationResponse{
+		Status:    http.StatusOK,
+		CommentID: commentID,
+	})
+}
+
+// ListCommentsHandler handles listing comments on a post.
+//
+// @Summary List comments on a post
+// @Description Fetch comments on a published post, the oldest first. The tree view pages through top-level comments
+// and nests all their replies, the flat view pages through every comment with parent_id set on replies.
+// @Tags comments
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param view query string false "Comments layout" Enums(tree, flat) default(tree)
+// @Param limit query int false "Number of comments per page" default(10)
+// @Param after query string false "Cursor of the next page returned by the previous request"
+// @Success 200 {object} models.CommentPage "Comments"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 404 {object} errors.ErrResponse "Post not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/comments [get]
+func (s *Server) ListCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Invalid pagination", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	page, err := s.postService.ListComments(ctx, postID, r.URL.Query().Get("view"), params.Limit, params.After)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error listing comments")
+		return
+	}
+
+	render.Render(w, r, page)
+}
+
+// UpdateCommentHandler handles editing a comment.
+//
+// @Summary Edit a comment
+// @Description Replace the body of a comment. Deleted comments cannot be edited.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Param data body models.UpdateCommentRequest true "Comment update request"
+// @Security BearerAuth
+// @Success 200 {object} models.CommentUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Comment not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/comments/{id} [put]
+func (s *Server) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	commentID := r.PathValue("id")
+
+	if commentID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "comment_id is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.UpdateCommentRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while updating comment", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	if err = s.postService.UpdateComment(ctx, commentID, data); err != nil {
+		s.renderServiceError(w, r, err, "Error updating comment")
+		return
+	}
+
+	render.Render(w, r, &models.CommentUpdateResponse{
+		Status:    http.StatusOK,
+		CommentID: commentID,
+	})
+}
+
+// DeleteCommentHandler handles deleting a comment.
+//
+// @Summary Delete a comment
+// @Description Remove the body of a comment. The comment stays in the tree marked as deleted, so replies to it are kept.
+// @Tags comments
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} models.CommentUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Comment not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/comments/{id} [delete]
+func (s *Server) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	commentID := r.PathValue("id")
+
+	if commentID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "comment_id is empty",
+		})
+		return
+	}
+
+	if err := s.postService.DeleteComment(ctx, commentID); err != nil {
+		s.renderServiceError(w, r, err, "Error deleting comment")
+		return
+	}
+
+	render.Render(w, r, &models.CommentUpdateResponse{
+		Status:    http.StatusOK,
+		CommentID: commentID,
+	})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	apierr "ndb/server/errors"
+	"ndb/server/services/audit"
+	"ndb/server/services/auth"
+	"ndb/server/services/posts"
+	"ndb/server/services/users"
+)
+
+// serviceErrors maps errors returned by the services to HTTP status codes, the first matching entry wins.
+var serviceErrors = []struct {
+	targets []error
+	status  int
+}{
+	{
+		targets: []error{auth.ErrInvalidCredentials, auth.ErrInvalidToken},
+		status:  http.StatusUnauthorized,
+	},
+	{
+		targets: []error{posts.ErrForbidden, users.ErrForbidden, auth.ErrForbidden, audit.ErrForbidden},
+		status:  http.StatusForbidden,
+	},
+	{
+		targets: []error{posts.ErrNotFound, users.ErrNotFound, auth.ErrNotFound, auth.ErrOIDCDisabled},
+		status:  http.StatusNotFound,
+	},
+	{
+		targets: []error{posts.ErrConflict, users.ErrConflict},
+		status:  http.StatusConflict,
+	},
+	{
+		targets: []error{posts.ErrInvalidInput, users.ErrInvalidInput, auth.ErrInvalidInput, audit.ErrInvalidInput},
+		status:  http.StatusBadRequest,
+	},
+}
+
+// renderServiceError logs the error returned by a service, together with attrs, and renders the matching
+// response. Errors which are not known to the services are rendered as internal server errors.
+func (s *Server) renderServiceError(w http.ResponseWriter, r *http.Request, err error, msg string, attrs ...any) {
+	s.log.ErrorContext(r.Context(), msg, append([]any{slog.Any("error", err)}, attrs...)...)
+
+	switch status := serviceErrorStatus(err); status {
+	case http.StatusForbidden:
+		render.Render(w, r, apierr.ErrForbidden)
+	case http.StatusNotFound:
+		render.Render(w, r, apierr.ErrNotFound)
+	case http.StatusInternalServerError:
+		render.Render(w, r, apierr.ErrInternalServerError)
+	default:
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: status,
+			Message:        err.Error(),
+		})
+	}
+}
+
+func serviceErrorStatus(err error) int {
+	for _, mapping := range serviceErrors {
+		for _, target := range mapping.targets {
+			if errors.Is(err, target) {
+				return mapping.status
+			}
+		}
+	}
+	return http.StatusInternalServerError
+}
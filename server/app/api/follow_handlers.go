@@ -0,0 +1,152 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+	"ndb/server/repositories/posts/model"
+)
+
+// FollowThreadHandler handles following a thread.
+//
+// @Summary Follow a thread
+// @Description Add posts of the thread to the feed of the authenticated user.
+// @Tags feed
+// @Produce json
+// @Param id path string true "Thread ID"
+// @Security BearerAuth
+// @Success 200 {object} models.FollowResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 404 {object} errors.ErrResponse "Thread not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/threads/{id}/follow [post]
+func (s *Server) FollowThreadHandler(w http.ResponseWriter, r *http.Request) {
+	s.handleFollow(w, r, model.FollowThread, true)
+}
+
+// UnfollowThreadHandler handles unfollowing a thread.
+//
+// @Summary Unfollow a thread
+// @Description Remove posts of the thread from the feed of the authenticated user.
+// @Tags feed
+// @Produce json
+// @Param id path string true "Thread ID"
+// @Security BearerAuth
+// @Success 200 {object} models.FollowResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/threads/{id}/follow [delete]
+func (s *Server) UnfollowThreadHandler(w http.ResponseWriter, r *http.Request) {
+	s.handleFollow(w, r, model.FollowThread, false)
+}
+
+// FollowUserHandler handles following an author.
+//
+// @Summary Follow an author
+// @Description Add posts written by the user, also as a co-author, to the feed of the authenticated user.
+// @Tags feed
+// @Produce json
+// @Param id path string true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} models.FollowResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 404 {object} errors.ErrResponse "User not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id}/follow [post]
+func (s *Server) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.handleFollow(w, r, model.FollowUser, true)
+}
+
+// UnfollowUserHandler handles unfollowing an author.
+//
+// @Summary Unfollow an author
+// @Description Remove posts written by the user from the feed of the authenticated user.
+// @Tags feed
+// @Produce json
+// @Param id path string true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} models.FollowResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id}/follow [delete]
+func (s *Server) UnfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.handleFollow(w, r, model.FollowUser, false)
+}
+
+func (s *Server) handleFollow(w http.ResponseWriter, r *http.Request, target model.FollowTarget, follow bool) {
+	ctx := r.Context()
+	targetID := r.PathValue("id")
+
+	if targetID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        string(target) + "_id is empty",
+		})
+		return
+	}
+
+	var err error
+	if follow {
+		err = s.postService.Follow(ctx, target, targetID)
+	} else {
+		err = s.postService.Unfollow(ctx, target, targetID)
+	}
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error changing follows", slog.Any("id", targetID))
+		return
+	}
+
+	render.Render(w, r, &models.FollowResponse{
+		Status:    http.StatusOK,
+		Type:      string(target),
+		ID:        targetID,
+		Following: follow,
+	})
+}
+
+// FeedHandler handles the feed of the authenticated user.
+//
+// @Summary Personalized feed
+// @Description Fetch a page of published posts from followed threads and by followed authors. Pass next_cursor
+// @Description of the previous page as after to get the next one.
+// @Tags feed
+// @Produce json
+// @Param limit query int false "Number of posts per page, 10 by default, up to 100"
+// @Param sort query string false "Sort order: created (default), updated or views"
+// @Param after query string false "Cursor returned as next_cursor of the previous page"
+// @Security BearerAuth
+// @Success 200 {object} models.PostPage "Page of posts"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/feed [get]
+func (s *Server) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse page parameters", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	page, err := s.postService.Feed(ctx, params.Limit, params.Sort, params.After)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error getting feed")
+		return
+	}
+
+	render.Render(w, r, page)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+
+	apierr "ndb/server/errors"
+	"ndb/server/services/auth"
+)
+
+const bearerPrefix = "Bearer "
+
+// authenticate puts the principal of a valid Bearer access token or API key into the request context. Requests
+// without the Authorization header pass through anonymously, requests with an invalid token are rejected.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(header)
+		if !ok {
+			render.Render(w, r, apierr.ErrUnauthorized)
+			return
+		}
+
+		ctx := r.Context()
+		authenticate := s.authService.Authenticate
+		if strings.HasPrefix(token, auth.APIKeyPrefix) {
+			authenticate = s.authService.AuthenticateAPIKey
+		}
+
+		principal, err := authenticate(ctx, token)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidToken) {
+				s.log.ErrorContext(ctx, "Error authenticating request", slog.Any("error", err))
+				render.Render(w, r, apierr.ErrInternalServerError)
+				return
+			}
+			render.Render(w, r, apierr.ErrUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(ctx, principal)))
+	})
+}
+
+// requireAuth rejects requests without a principal in the context.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.PrincipalFrom(r.Context()); !ok {
+			render.Render(w, r, apierr.ErrUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// principal returns the principal of a request passed through requireAuth.
+func principal(r *http.Request) *auth.Principal {
+	p, _ := auth.PrincipalFrom(r.Context())
+	return p
+}
+
+func bearerToken(header string) (string, bool) {
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):]), true
+}
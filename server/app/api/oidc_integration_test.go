@@ -0,0 +1,152 @@
+//go:build integration
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"ndb/server/config"
+	poststore "ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/auth"
+)
+
+// TestOIDCLogin logs in through the mock provider from docker-compose and checks the user it provisions.
+// Run it with the compose services up: go test -tags integration ./server/app/api/
+func TestOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.OIDC.Issuer = envOr("OIDC_ISSUER", "http://localhost:8081/default")
+	cfg.OIDC.ClientID = envOr("OIDC_CLIENT_ID", "minimal-blog")
+	cfg.OIDC.ClientSecret = envOr("OIDC_CLIENT_SECRET", "secret")
+	cfg.OIDC.RedirectURL = "http://localhost:8080/api/v1/auth/oidc/callback"
+	cfg.OIDC.FrontendURL = "http://localhost:3000/login"
+	cfg.OIDC.RoleClaim = "groups"
+	cfg.OIDC.Roles = map[string]string{"blog-editors": "editor"}
+
+	store, err := poststore.NewStore(ctx, logger, &cfg.Neo4j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.EnsureIndexes(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	authService, err := auth.NewService(
+		redis.NewClient(&redis.Options{Addr: cfg.Redis.Address}),
+		store,
+		&cfg.Auth,
+		&cfg.OIDC,
+		logger,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &Server{log: logger, authService: authService, oidcFrontendURL: cfg.OIDC.FrontendURL}
+
+	// Login redirects to the provider
+	rec := httptest.NewRecorder()
+	srv.OIDCLoginHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: got status %d, want %d: %s", rec.Code, http.StatusFound, rec.Body)
+	}
+	authorizeURL := rec.Header().Get("Location")
+	if !strings.HasPrefix(authorizeURL, cfg.OIDC.Issuer) {
+		t.Fatalf("login: redirected to %s, want the provider %s", authorizeURL, cfg.OIDC.Issuer)
+	}
+
+	// The interactive login form of the mock provider takes the subject and the claims of the ID token
+	subject := fmt.Sprintf("oidc-test-%d", time.Now().UnixNano())
+	claims, err := json.Marshal(map[string]any{
+		"email":              subject + "@example.com",
+		"email_verified":     true,
+		"preferred_username": subject,
+		"groups":             []string{"blog-editors"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.PostForm(authorizeURL, url.Values{"username": {subject}, "claims": {string(claims)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("provider login: got status %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	callbackURL, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The callback provisions the user and passes the tokens to the frontend
+	rec = httptest.NewRecorder()
+	srv.OIDCCallbackHandler(
+		rec,
+		httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?"+callbackURL.RawQuery, nil),
+	)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("callback: got status %d, want %d: %s", rec.Code, http.StatusFound, rec.Body)
+	}
+	frontendURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragment, err := url.ParseQuery(frontendURL.Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fragment.Has("error") {
+		t.Fatalf("callback: login failed: %s: %s", fragment.Get("error"), fragment.Get("error_description"))
+	}
+	if got := frontendURL.Scheme + "://" + frontendURL.Host + frontendURL.Path; got != cfg.OIDC.FrontendURL {
+		t.Errorf("callback: redirected to %s, want %s", got, cfg.OIDC.FrontendURL)
+	}
+
+	principal, err := authService.Authenticate(ctx, fragment.Get("access_token"))
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if principal.Username != subject || principal.Role != model.RoleEditor {
+		t.Errorf("principal: got %s with role %s, want %s with role %s",
+			principal.Username, principal.Role, subject, model.RoleEditor)
+	}
+
+	user, err := store.GetUserByIdentity(ctx, cfg.OIDC.Issuer, subject)
+	if err != nil {
+		t.Fatalf("provisioned user: %v", err)
+	}
+	if user.UserID != principal.UserID || user.Email != subject+"@example.com" {
+		t.Errorf("provisioned user: got %s (%s), want %s (%s@example.com)",
+			user.UserID, user.Email, principal.UserID, subject)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+
+	defaultRelatedLimit = 5
+	maxRelatedLimit     = 20
+
+	defaultTagLimit = 50
+	maxTagLimit     = 200
+)
+
+// pageParams holds the pagination query parameters shared by listing endpoints.
+type pageParams struct {
+	Limit int
+	Sort  string
+	After string
+}
+
+func parsePageParams(r *http.Request) (*pageParams, error) {
+	query := r.URL.Query()
+	params := &pageParams{
+		Sort:  query.Get("sort"),
+		After: query.Get("after"),
+	}
+
+	var err error
+	if params.Limit, err = parseLimit(r, defaultPageLimit, maxPageLimit); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// parseLimit returns the limit query parameter, or def when it is missing.
+func parseLimit(r *http.Request, def, maxLimit int) (int, error) {
+	limit := r.URL.Query().Get("limit")
+	if limit == "" {
+		return def, nil
+	}
+
+	l, err := strconv.Atoi(limit)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse limit: %w", err)
+	}
+	if l < 1 || l > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+
+	return l, nil
+}
@@ -5,29 +5,42 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/render"
 
 	"ndb/server/app/models"
-	"ndb/server/errors"
+	apierr "ndb/server/errors"
 )
 
 // CreatePostHandler handles the creation of a new post along with a markdown file upload.
 //
 // @Summary Create a new post with a markdown file
-// @Description This endpoint allows users to create a new post by submitting text data (title, content, thread, user_id) and a markdown file (.md).
+// @Description This endpoint allows users to create a new post by submitting text data (title, content, thread) and a markdown file (.md).
+// The authenticated user becomes the author of the post.
 // The markdown file is saved in the user's designated S3 bucket, and the post details are saved in MongoDB.
+// YAML (---) or TOML (+++) front matter of the file provides title, thread, description, tags, date and cover_image,
+// form fields take precedence over it. The front matter block is not stored with the markdown content.
 // @Tags posts
 // @Accept multipart/form-data
 // @Produce json
 // @Param markdown formData file true "Markdown File"
-// @Param title formData string true "Title of the post"
-// @Param thread formData string true "ID of the thread to which the post belongs"
-// @Param user_id formData integer true "ID of the user creating the post"
+// @Param title formData string false "Title of the post, required unless set in front matter"
+// @Param thread formData string false "ID of the thread to which the post belongs, required unless set in front matter"
+// @Param draft formData boolean false "Keep the post as a draft instead of publishing it"
+// @Param publish_at formData string false "RFC3339 date at which the post gets published"
+// @Param tags formData []string false "Tags of the post, repeated or comma separated, replace tags from front matter" collectionFormat(multi)
+// @Security BearerAuth
 // @Success 200 {object} models.PostCreationResponse
 // @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Thread or user not found"
 // @Failure 500 {object} errors.ErrResponse "Internal Server Error"
 // @Router /api/v1/posts [post]
 func (s *Server) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
@@ -36,14 +49,14 @@ func (s *Server) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseMultipartForm(10 << 20) // 10MB
 	if err != nil {
 		s.log.ErrorContext(ctx, "Unable to parse form", slog.Any("error", err))
-		render.Render(w, r, errors.ErrBadRequest)
+		render.Render(w, r, apierr.ErrBadRequest)
 		return
 	}
 
 	form := r.MultipartForm
 	if form == nil {
 		s.log.ErrorContext(ctx, "No multipart form data found", slog.Any("error", err))
-		render.Render(w, r, &errors.ErrResponse{
+		render.Render(w, r, &apierr.ErrResponse{
 			Err:            fmt.Errorf("multipart form is nil"),
 			HTTPStatusCode: http.StatusBadRequest,
 			Message:        "Invalid form data",
@@ -51,34 +64,40 @@ func (s *Server) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate required fields
-	requiredFields := []string{"title", "thread", "user_id"}
-	if err = validatePostForm(form.Value, requiredFields...); err != nil {
-		s.log.ErrorContext(ctx, "Form validation error", slog.Any("error", err))
-		render.Render(w, r, &errors.ErrResponse{
+	// Extract fields from the form, title and thread may come from the front matter instead
+	title := formValue(form.Value, "title")
+	thread := formValue(form.Value, "thread")
+
+	var draft bool
+	if value := formValue(form.Value, "draft"); value != "" {
+		draft, err = strconv.ParseBool(value)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Cannot parse draft flag", slog.Any("error", err))
+			render.Render(w, r, &apierr.ErrResponse{
+				Err:            err,
+				HTTPStatusCode: http.StatusBadRequest,
+				Message:        "draft must be a boolean",
+			})
+			return
+		}
+	}
+
+	publishAt, err := formTime(form.Value, "publish_at")
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse publish date", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
 			Err:            err,
 			HTTPStatusCode: http.StatusBadRequest,
-			Message:        err.Error(),
+			Message:        "publish_at must be a RFC3339 date",
 		})
 		return
 	}
 
-	// Extract fields from the form
-	title := form.Value["title"][0]
-	thread := form.Value["thread"][0]
-	user := form.Value["user_id"][0]
-
-	userID, err := strconv.Atoi(user)
-	if err != nil {
-		s.log.ErrorContext(ctx, "Cannot parse userID", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
-	}
-
 	// Handle markdown file
 	files := form.File["markdown"]
 	if len(files) == 0 {
 		s.log.ErrorContext(ctx, "No markdown file found in form", slog.Any("error", err))
-		render.Render(w, r, &errors.ErrResponse{
+		render.Render(w, r, &apierr.ErrResponse{
 			Err:            fmt.Errorf("no markdown file provided"),
 			HTTPStatusCode: http.StatusBadRequest,
 			Message:        "No markdown file provided",
@@ -90,22 +109,24 @@ func (s *Server) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	file, err := mdFile.Open()
 	if err != nil {
 		s.log.ErrorContext(ctx, "Error opening markdown file", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		render.Render(w, r, apierr.ErrInternalServerError)
 		return
 	}
 	defer file.Close()
 
 	// Process the post creation
 	data := models.CreatePostRequest{
-		Title:  title,
-		Thread: thread,
-		UserID: int64(userID),
+		Title:     title,
+		Thread:    thread,
+		UserID:    principal(r).UserID,
+		Draft:     draft,
+		PublishAt: publishAt,
+		Tags:      formList(form.Value, "tags"),
 	}
 
 	postID, err := s.postService.CreatePost(ctx, file, &data)
 	if err != nil {
-		s.log.ErrorContext(ctx, "Error creating post", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		s.renderServiceError(w, r, err, "Error creating post")
 		return
 	}
 
@@ -116,56 +137,460 @@ func (s *Server) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func validatePostForm(form map[string][]string, requiredFields ...string) error {
-	for _, field := range requiredFields {
-		if len(form[field]) == 0 {
-			return fmt.Errorf("missing or empty field: %s", field)
+// UpdatePostHandler handles the replacement of a post markdown file and metadata.
+//
+// @Summary Update a post
+// @Description This endpoint allows users to replace the markdown file (.md) of an existing post and/or change its title and thread.
+// The markdown file is stored in S3 as a new revision of the post, previous revisions are kept.
+// Front matter of the file is handled the same way as on post creation.
+// @Tags posts
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param markdown formData file false "Markdown File"
+// @Param title formData string false "New title of the post"
+// @Param thread formData string false "ID of the thread to which the post should be moved"
+// @Param tags formData []string false "Tags replacing the tags of the post, repeated or comma separated" collectionFormat(multi)
+// @Param publish_at formData string false "RFC3339 date at which the draft or scheduled post gets published"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Post or thread not found"
+// @Failure 409 {object} errors.ErrResponse "Post was modified concurrently"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id} [put]
+func (s *Server) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	err := r.ParseMultipartForm(10 << 20) // 10MB
+	if err != nil {
+		s.log.ErrorContext(ctx, "Unable to parse form", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	form := r.MultipartForm
+	if form == nil {
+		s.log.ErrorContext(ctx, "No multipart form data found")
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            fmt.Errorf("multipart form is nil"),
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "Invalid form data",
+		})
+		return
+	}
+
+	publishAt, err := formTime(form.Value, "publish_at")
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse publish date", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "publish_at must be a RFC3339 date",
+		})
+		return
+	}
+
+	data := models.UpdatePostRequest{
+		Title:     formValue(form.Value, "title"),
+		Thread:    formValue(form.Value, "thread"),
+		UserID:    principal(r).UserID,
+		Tags:      formList(form.Value, "tags"),
+		PublishAt: publishAt,
+	}
+
+	var file multipart.File
+	if files := form.File["markdown"]; len(files) > 0 {
+		file, err = files[0].Open()
+		if err != nil {
+			s.log.ErrorContext(ctx, "Error opening markdown file", slog.Any("error", err))
+			render.Render(w, r, apierr.ErrInternalServerError)
+			return
+		}
+		defer file.Close()
+	}
+
+	if file == nil && data.Title == "" && data.Thread == "" && len(data.Tags) == 0 && data.PublishAt.IsZero() {
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            fmt.Errorf("nothing to update"),
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "Provide a markdown file, title, thread, tags or publish date",
+		})
+		return
+	}
+
+	s.updatePost(w, r, postID, file, &data)
+}
+
+// PatchPostHandler handles the update of post metadata.
+//
+// @Summary Update post metadata
+// @Description Change the title, thread and front matter metadata of an existing post without touching its markdown file.
+// Setting publish_at of a draft or scheduled post schedules it, the post gets published by the background publisher.
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param data body models.UpdatePostRequest true "Post update request"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Post or thread not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id} [patch]
+func (s *Server) PatchPostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.UpdatePostRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while updating post", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	if data.Title == "" && data.Thread == "" && data.Description == "" && len(data.Tags) == 0 &&
+		data.CoverImage == "" && data.PublishDate.IsZero() && data.PublishAt.IsZero() {
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            fmt.Errorf("nothing to update"),
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "Provide a title, thread, publish date or front matter field",
+		})
+		return
+	}
+
+	s.updatePost(w, r, postID, nil, data)
+}
+
+func (s *Server) updatePost(
+	w http.ResponseWriter,
+	r *http.Request,
+	postID string,
+	file multipart.File,
+	data *models.UpdatePostRequest,
+) {
+	ctx := r.Context()
+
+	err := s.postService.UpdatePost(ctx, postID, file, data)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error updating post", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Render(w, r, &models.PostUpdateResponse{
+		Status: http.StatusOK,
+		PostID: postID,
+	})
+}
+
+// DeletePostHandler handles moving a post to trash.
+//
+// @Summary Delete a post
+// @Description Mark the post as deleted. It is hidden from listings and permanently removed after the trash retention period, unless restored.
+// @Tags posts
+// @Produce json
+// @Param id path string true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} models.PostDeletionResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Post not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id} [delete]
+func (s *Server) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	deletedAt, err := s.postService.DeletePost(ctx, postID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error deleting post", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Render(w, r, &models.PostDeletionResponse{
+		Status:    http.StatusOK,
+		PostID:    postID,
+		DeletedAt: deletedAt,
+	})
+}
+
+// RestorePostHandler handles bringing a post back from trash.
+//
+// @Summary Restore a deleted post
+// @Description Restore a post from trash to the status it had before deletion. Posts of deleted threads stay in trash.
+// @Tags posts
+// @Produce json
+// @Param id path string true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Deleted post not found"
+// @Failure 409 {object} errors.ErrResponse "Thread of the post is deleted"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/restore [post]
+func (s *Server) RestorePostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	err := s.postService.RestorePost(ctx, postID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error restoring post", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Render(w, r, &models.PostUpdateResponse{
+		Status: http.StatusOK,
+		PostID: postID,
+	})
+}
+
+// PublishPostHandler handles publishing a draft or scheduled post right away.
+//
+// @Summary Publish a post
+// @Description Publish the draft or scheduled post now, posts turned back into drafts are published again the same way.
+// Authors publish their own posts, editors and admins any post.
+// @Tags posts
+// @Produce json
+// @Param id path string true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Draft or scheduled post not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/publish [post]
+func (s *Server) PublishPostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	err := s.postService.PublishPost(ctx, postID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error publishing post", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Render(w, r, &models.PostUpdateResponse{
+		Status: http.StatusOK,
+		PostID: postID,
+	})
+}
+
+// UnpublishPostHandler handles turning a published post back into a draft.
+//
+// @Summary Unpublish a post
+// @Description Turn the published post back into a draft, it can be published again with /api/v1/posts/{id}/publish.
+// Authors unpublish their own posts, editors and admins any post.
+// @Tags posts
+// @Produce json
+// @Param id path string true "Post ID"
+// @Security BearerAuth
+// @Success 200 {object} models.PostUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Published post not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/unpublish [post]
+func (s *Server) UnpublishPostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	err := s.postService.UnpublishPost(ctx, postID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error unpublishing post", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Render(w, r, &models.PostUpdateResponse{
+		Status: http.StatusOK,
+		PostID: postID,
+	})
+}
+
+// ListDeletedPostsHandler fetches the posts in trash.
+//
+// @Summary List deleted posts
+// @Description Fetch posts which were deleted and not yet purged, most recently deleted first. Only editors and admins can view trash.
+// @Tags posts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Post "Deleted posts"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Trash is empty"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/trash [get]
+func (s *Server) ListDeletedPostsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	postList, err := s.postService.ListDeletedPosts(ctx)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch deleted posts")
+		return
+	}
+
+	render.Respond(w, r, postList)
+}
+
+// ListDraftPostsHandler fetches the posts of a user which are not published yet.
+//
+// @Summary List draft posts
+// @Description Fetch draft and scheduled posts of the authenticated user, most recently updated first.
+// @Tags posts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Post "Draft posts"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 404 {object} errors.ErrResponse "No drafts found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/drafts [get]
+func (s *Server) ListDraftPostsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := principal(r).UserID
+
+	postList, err := s.postService.ListDraftPosts(ctx, userID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch draft posts", slog.Any("user_id", userID))
+		return
+	}
+
+	render.Respond(w, r, postList)
+}
+
+// visitorID identifies the client for view deduplication.
+func (s *Server) visitorID(r *http.Request) string {
+	return s.clientIP(r) + "|" + r.UserAgent()
+}
+
+func formValue(form map[string][]string, field string) string {
+	if len(form[field]) == 0 {
+		return ""
+	}
+	return form[field][0]
+}
+
+// formTime parses the field as a RFC3339 date, missing field gives zero time.
+func formTime(form map[string][]string, field string) (time.Time, error) {
+	value := formValue(form, field)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// formList returns all values of a repeated field, each of them may hold a comma separated list.
+func formList(form map[string][]string, field string) []string {
+	var list []string
+	for _, value := range form[field] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" && !slices.Contains(list, item) {
+				list = append(list, item)
+			}
 		}
 	}
-	return nil
+	return list
 }
 
 // GetPostListsHandler handles the fetching of a post data.
 //
 // @Summary Retrieve post data
-// @Description Fetch post details from Neo4j. The response contains post details in JSON format followed by the image file.
+// @Description Fetch a page of published posts from all threads. Pass next_cursor of the previous page as after to get the next one.
 // @Tags posts
 // @Accept json
 // @Produce json
-// @Param limit query int false "limit"
+// @Param limit query int false "Number of posts per page" default(10)
+// @Param sort query string false "Sort order" Enums(created, updated, views) default(created)
+// @Param after query string false "Cursor of the previous page"
 // @Header 200 {string} Content-Type "application/json"
-// @Success 200 {object} []models.Post "Posts"
-// @Failure 400 {object} errors.ErrResponse "Invalid request or post not found"
+// @Success 200 {object} models.PostPage "Posts"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
 // @Failure 500 {object} errors.ErrResponse "Internal server error"
 // @Router /api/v1/posts [get]
 func (s *Server) GetPostListsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	limit := r.URL.Query().Get("limit")
 
-	if limit == "" {
-		limit = "3"
-	}
-
-	l, err := strconv.Atoi(limit)
-	if err != nil {
-		s.log.ErrorContext(ctx, "Cannot parse limit", slog.Any("error", err))
-		render.Render(w, r, errors.ErrBadRequest)
-	}
-
-	posts, err := s.postService.GetPostsWithLimit(ctx, l)
+	params, err := parsePageParams(r)
 	if err != nil {
-		s.log.ErrorContext(ctx, "Error getting posts", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		s.log.ErrorContext(ctx, "Cannot parse page parameters", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
 	}
 
-	// Write posts data as JSON
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(posts)
+	page, err := s.postService.ListPosts(ctx, params.Limit, params.Sort, params.After)
 	if err != nil {
-		s.log.ErrorContext(ctx, "Error encoding post", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		s.renderServiceError(w, r, err, "Error getting posts")
 		return
 	}
+
+	render.Render(w, r, page)
 }
 
 // GetPostHandler handles the fetching of post metadata.
@@ -178,6 +603,7 @@ func (s *Server) GetPostListsHandler(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Post ID"
 // @Success 200 {object} models.Post "Post metadata"
 // @Failure 400 {object} errors.ErrResponse "Invalid request or post not found"
+// @Failure 404 {object} errors.ErrResponse "Post not found"
 // @Failure 500 {object} errors.ErrResponse "Internal server error"
 // @Router /api/v1/posts/{id} [get]
 func (s *Server) GetPostHandler(w http.ResponseWriter, r *http.Request) {
@@ -185,36 +611,121 @@ func (s *Server) GetPostHandler(w http.ResponseWriter, r *http.Request) {
 	postID := r.PathValue("id")
 
 	if postID == "" {
-		render.Render(w, r, &errors.ErrResponse{
+		render.Render(w, r, &apierr.ErrResponse{
 			HTTPStatusCode: http.StatusBadRequest,
 			Message:        "post_id is empty",
 		})
 		return
 	}
 
-	post, err := s.postService.GetPostMetadata(ctx, postID)
+	post, err := s.postService.GetPostMetadata(ctx, postID, s.visitorID(r))
 	if err != nil {
-		s.log.ErrorContext(ctx, "Error getting post metadata", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		s.renderServiceError(w, r, err, "Error getting post metadata")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err = json.NewEncoder(w).Encode(post); err != nil {
 		s.log.ErrorContext(ctx, "Error encoding post metadata", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		render.Render(w, r, apierr.ErrInternalServerError)
 	}
 }
 
+// GetPostHTMLHandler handles the fetching of a post rendered to HTML.
+//
+// @Summary Retrieve post rendered to HTML
+// @Description Render the post markdown (GitHub flavored, with tables, task lists and footnotes) to sanitized HTML.
+// Headings get IDs and anchor links. The rendered content is cached in Redis.
+// @Tags posts
+// @Produce html
+// @Param id path string true "Post ID"
+// @Success 200 {string} string "Rendered post"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 404 {object} errors.ErrResponse "Post not found"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/posts/{id}/html [get]
+func (s *Server) GetPostHTMLHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	html, err := s.postService.GetPostHTML(ctx, postID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error rendering post", slog.Any("post_id", postID))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err = w.Write(html); err != nil {
+		s.log.ErrorContext(ctx, "Error writing html to response", slog.Any("error", err))
+	}
+}
+
+// RelatedPostsHandler handles the fetching of posts to read after a post.
+//
+// @Summary Retrieve related posts
+// @Description Fetch published posts related to the published post, the most related first. Posts are scored by
+// @Description shared tags of the posts and their threads, the same thread, the same author and users who read
+// @Description both posts. reasons of each post are same_author, shared_tags, same_thread or co_read.
+// @Tags posts
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param limit query int false "Number of posts" default(5)
+// @Success 200 {array} models.RelatedPost "Related posts"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 404 {object} errors.ErrResponse "Post not found"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/posts/{id}/related [get]
+func (s *Server) RelatedPostsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	limit, err := parseLimit(r, defaultRelatedLimit, maxRelatedLimit)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse limit", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	related, err := s.postService.RelatedPosts(ctx, postID, limit)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error getting related posts", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Respond(w, r, related)
+}
+
 // GetMarkdownHandler handles the fetching of a post markdown file.
 //
 // @Summary Retrieve post markdown file
-// @Description Fetch the markdown file associated with a post from S3.
+// @Description Fetch the current markdown file of a post from S3. Drafts and scheduled posts are served only
+// @Description to those allowed to edit them, files of older revisions and of posts in trash are not served.
 // @Tags files
 // @Produce text/markdown
 // @Param id path string true "Content File ID"
 // @Success 200 {file} file "Markdown file"
-// @Failure 400 {object} errors.ErrResponse "Invalid request or post not found"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 404 {object} errors.ErrResponse "File not found"
 // @Failure 500 {object} errors.ErrResponse "Internal server error"
 // @Router /api/v1/files/{id} [get]
 func (s *Server) GetMarkdownHandler(w http.ResponseWriter, r *http.Request) {
@@ -222,7 +733,7 @@ func (s *Server) GetMarkdownHandler(w http.ResponseWriter, r *http.Request) {
 	fileName := r.PathValue("id")
 
 	if fileName == "" {
-		render.Render(w, r, &errors.ErrResponse{
+		render.Render(w, r, &apierr.ErrResponse{
 			HTTPStatusCode: http.StatusBadRequest,
 			Message:        "file_name is empty",
 		})
@@ -231,8 +742,7 @@ func (s *Server) GetMarkdownHandler(w http.ResponseWriter, r *http.Request) {
 
 	file, err := s.postService.GetPostMarkdown(ctx, fileName)
 	if err != nil {
-		s.log.ErrorContext(ctx, "Error getting post markdown", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		s.renderServiceError(w, r, err, "Error getting post markdown", slog.Any("file_name", fileName))
 		return
 	}
 	defer file.Close()
@@ -244,6 +754,6 @@ func (s *Server) GetMarkdownHandler(w http.ResponseWriter, r *http.Request) {
 	// Stream the markdown file to the response
 	if _, err = io.Copy(w, file); err != nil {
 		s.log.ErrorContext(ctx, "Error writing markdown to response", slog.Any("error", err))
-		render.Render(w, r, errors.ErrInternalServerError)
+		render.Render(w, r, apierr.ErrInternalServerError)
 	}
 }
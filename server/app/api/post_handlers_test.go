@@ -0,0 +1,43 @@
+package api
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFormList(t *testing.T) {
+	tests := []struct {
+		name string
+		form map[string][]string
+		want []string
+	}{
+		{
+			name: "missing field",
+			form: map[string][]string{"other": {"go"}},
+			want: nil,
+		},
+		{
+			name: "repeated field",
+			form: map[string][]string{"tags": {"go", "databases"}},
+			want: []string{"go", "databases"},
+		},
+		{
+			name: "comma separated",
+			form: map[string][]string{"tags": {"go, databases,testing"}},
+			want: []string{"go", "databases", "testing"},
+		},
+		{
+			name: "empty items and duplicates are skipped",
+			form: map[string][]string{"tags": {"go,, ,databases", "go", ""}},
+			want: []string{"go", "databases"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formList(tt.form, "tags"); !slices.Equal(got, tt.want) {
+				t.Errorf("formList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+
+	apierr "ndb/server/errors"
+	"ndb/server/services/auth"
+)
+
+// rateLimits holds the number of requests allowed per window in a route group for each kind of identity,
+// 0 disables the limit.
+type rateLimits struct {
+	ip     int
+	user   int
+	apiKey int
+}
+
+// rateLimit counts requests of the route group per API key, user or, for anonymous requests, IP and rejects
+// the ones over the limit. It has to run after authenticate. When redis can't be reached requests are let through.
+func (s *Server) rateLimit(group string, limits rateLimits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.rateLimitCfg.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, limit := "ip:"+s.clientIP(r), limits.ip
+			if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+				if principal.APIKeyID != "" {
+					identity, limit = "key:"+principal.APIKeyID, limits.apiKey
+				} else {
+					identity, limit = "user:"+principal.UserID, limits.user
+				}
+			}
+
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			result, err := s.limiter.Allow(ctx, group+":"+identity, limit)
+			if err != nil {
+				s.log.ErrorContext(ctx, "Rate limit check failed, letting request through", slog.Any("error", err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reset := strconv.Itoa(seconds(result.Reset))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", reset)
+			w.Header().Set("RateLimit-Policy", strconv.Itoa(result.Limit)+";w="+strconv.Itoa(seconds(s.limiter.Window())))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", reset)
+				render.Render(w, r, apierr.ErrTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds the duration up to whole seconds, so clients don't retry too early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+)
+
+// ListRevisionsHandler fetches the revision history of a post.
+//
+// @Summary List post revisions
+// @Description Fetch revisions of the post markdown file, the newest first.
+// @Tags revisions
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {array} models.Revision "Revisions"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 404 {object} errors.ErrResponse "Post not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/revisions [get]
+func (s *Server) ListRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	revisions, err := s.postService.ListRevisions(ctx, postID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch revisions", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Respond(w, r, revisions)
+}
+
+// DiffRevisionsHandler returns the difference between two revisions of a post.
+//
+// @Summary Diff post revisions
+// @Description Return a unified diff of the post markdown file between two revisions.
+// @Tags revisions
+// @Produce plain
+// @Param id path string true "Post ID"
+// @Param from query int true "Revision to diff from"
+// @Param to query int true "Revision to diff to"
+// @Success 200 {string} string "Unified diff"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 404 {object} errors.ErrResponse "Post or revision not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/revisions/diff [get]
+func (s *Server) DiffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse from revision", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "from must be a revision number",
+		})
+		return
+	}
+
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse to revision", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "to must be a revision number",
+		})
+		return
+	}
+
+	diff, err := s.postService.DiffRevisions(ctx, postID, from, to)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to diff revisions", slog.Any("post_id", postID))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err = io.WriteString(w, diff); err != nil {
+		s.log.ErrorContext(ctx, "Error writing diff to response", slog.Any("error", err))
+	}
+}
+
+// RestoreRevisionHandler rolls the post content back to one of its revisions.
+//
+// @Summary Restore post revision
+// @Description Make the content of the revision current again. The restored content is recorded as a new revision.
+// @Tags revisions
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param rev path int true "Revision to restore"
+// @Security BearerAuth
+// @Success 200 {object} models.RevisionRestoreResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Post or revision not found"
+// @Failure 409 {object} errors.ErrResponse "Post was modified concurrently"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/posts/{id}/revisions/{rev}/restore [post]
+func (s *Server) RestoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	postID := r.PathValue("id")
+
+	if postID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "post_id is empty",
+		})
+		return
+	}
+
+	number, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse revision", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "rev must be a revision number",
+		})
+		return
+	}
+
+	revision, err := s.postService.RestoreRevision(ctx, postID, number, principal(r).UserID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to restore revision", slog.Any("post_id", postID))
+		return
+	}
+
+	render.Render(w, r, &models.RevisionRestoreResponse{
+		Status:   http.StatusOK,
+		PostID:   postID,
+		Revision: revision,
+	})
+}
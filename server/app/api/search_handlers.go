@@ -0,0 +1,57 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	apierr "ndb/server/errors"
+)
+
+// SearchHandler handles the full-text search of posts.
+//
+// @Summary Search posts
+// @Description Search published posts by words in their title, markdown content, thread name or tags.
+// Results are ordered by relevance and contain a snippet of the content with matches wrapped in <mark>.
+// @Tags search
+// @Produce json
+// @Param q query string true "Words to search for"
+// @Param thread query string false "ID of the thread to search in"
+// @Param tag query string false "Tag of the thread or post, its aliases and descendants match too"
+// @Param limit query int false "Number of results per page" default(10)
+// @Param after query string false "Cursor of the next page returned by the previous request"
+// @Success 200 {object} models.SearchPage "Search results"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/search [get]
+func (s *Server) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Invalid pagination", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	page, err := s.postService.Search(
+		ctx,
+		query.Get("q"),
+		query.Get("thread"),
+		query.Get("tag"),
+		params.Limit,
+		params.After,
+	)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error searching posts")
+		return
+	}
+
+	render.Render(w, r, page)
+}
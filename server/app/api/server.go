@@ -5,35 +5,59 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"ndb/server/services/audit"
+	"ndb/server/services/auth"
 	"ndb/server/services/file"
 	"ndb/server/services/posts"
+	"ndb/server/services/ratelimit"
+	"ndb/server/services/users"
+	"ndb/server/services/views"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/render"
 	slogchi "github.com/samber/slog-chi"
 	httpSwagger "github.com/swaggo/http-swagger"
 	s3client "ndb/server/clients/aws"
 	"ndb/server/config"
+	logrepo "ndb/server/repositories/log"
 	poststore "ndb/server/repositories/posts"
 )
 
 type Server struct {
 	*config.HTTPServer
-	log    *slog.Logger
-	router *chi.Mux
+	log      *slog.Logger
+	router   *chi.Mux
+	postsCfg *config.Posts
+	viewsCfg *config.Views
 
-	postService *posts.Service
+	rateLimitCfg *config.RateLimit
+	limiter      *ratelimit.Limiter
+	// trustedProxies are the addresses whose X-Forwarded-For header is trusted
+	trustedProxies []netip.Prefix
+	// oidcFrontendURL is the page OIDC logins end on
+	oidcFrontendURL string
+
+	postService  *posts.Service
+	viewService  *views.Service
+	userService  *users.Service
+	authService  *auth.Service
+	auditService *audit.Service
 }
 
 func NewServer(
 	ctx context.Context,
 	logger *slog.Logger,
 	cfg *config.Config,
+	logStore *logrepo.Store,
 ) (*Server, error) {
 	s3Client, err := s3client.New(ctx, logger, &cfg.S3)
 	if err != nil {
@@ -45,21 +69,67 @@ func NewServer(
 		return nil, err
 	}
 
+	if err = postStore.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
 	cachedFileService := file.NewCachedService(s3Client, &cfg.Redis, logger)
+	viewService := views.NewService(cachedFileService.Client(), postStore, &cfg.Views, logger)
+	auditService := audit.NewService(logStore, logger)
+
+	trustedProxies, err := parseTrustedProxies(cfg.HTTPServer.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.OIDC.Issuer != "" && cfg.OIDC.FrontendURL == "" {
+		return nil, errors.New("OIDC frontend URL is required when OIDC login is enabled")
+	}
+
+	userService, err := users.NewService(cachedFileService, postStore, &cfg.Auth, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	authService, err := auth.NewService(cachedFileService.Client(), postStore, &cfg.Auth, &cfg.OIDC, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	postService := posts.NewService(
+		cachedFileService,
+		postStore,
+		viewService,
+		auditService,
+		cachedFileService.Client(),
+		&cfg.Posts,
+		logger,
+	)
 
 	srv := &Server{
-		HTTPServer:  &cfg.HTTPServer,
-		log:         logger,
-		router:      chi.NewRouter(),
-		postService: posts.NewService(cachedFileService, postStore, logger),
+		HTTPServer:      &cfg.HTTPServer,
+		log:             logger,
+		router:          chi.NewRouter(),
+		postsCfg:        &cfg.Posts,
+		viewsCfg:        &cfg.Views,
+		rateLimitCfg:    &cfg.RateLimit,
+		limiter:         ratelimit.NewLimiter(cachedFileService.Client(), cfg.RateLimit.Window, logger),
+		trustedProxies:  trustedProxies,
+		oidcFrontendURL: cfg.OIDC.FrontendURL,
+		postService:     postService,
+		viewService:     viewService,
+		userService:     userService,
+		authService:     authService,
+		auditService:    auditService,
 	}
 
+	srv.router.Use(middleware.RequestID)
 	srv.router.Use(slogchi.NewWithConfig(logger, slogchi.Config{
 		DefaultLevel:     slog.LevelInfo,
 		ClientErrorLevel: slog.LevelError,
 		ServerErrorLevel: slog.LevelError,
 		WithUserAgent:    true,
-		WithRequestID:    false,
+		WithRequestID:    true,
 	}))
 	srv.routes()
 
@@ -75,6 +145,9 @@ func (s *Server) Start(ctx context.Context) {
 		WriteTimeout: s.HTTPServer.WriteTimeout,
 	}
 
+	stopWorkers := s.startWorkers(ctx)
+	defer stopWorkers()
+
 	shutdownComplete := handleShutdown(func() {
 		if err := server.Shutdown(ctx); err != nil {
 			s.log.ErrorContext(ctx, "Server shutdown failed", slog.Any("error", err))
@@ -93,6 +166,100 @@ func (s *Server) Start(ctx context.Context) {
 	s.log.InfoContext(ctx, "Shutdown gracefully")
 }
 
+// startWorkers runs the background workers and returns a function which stops them and waits until they finish.
+func (s *Server) startWorkers(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+
+	workers := []func(context.Context){
+		s.purgeWorker,
+		s.publishWorker,
+		s.viewsWorker,
+	}
+
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(ctx)
+		}()
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// purgeWorker periodically removes posts which have been in trash longer than the retention period.
+func (s *Server) purgeWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.postsCfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.postService.PurgeDeletedPosts(ctx, s.postsCfg.TrashRetention)
+			if err != nil {
+				s.log.ErrorContext(ctx, "Failed to purge deleted posts", slog.Any("error", err))
+			}
+			if purged > 0 {
+				s.log.InfoContext(ctx, "Purged deleted posts", slog.Int("purged", purged))
+			}
+		}
+	}
+}
+
+// publishWorker periodically publishes draft and scheduled posts whose publish date has passed.
+func (s *Server) publishWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.postsCfg.PublishInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := s.postService.PublishDuePosts(ctx)
+			if err != nil {
+				s.log.ErrorContext(ctx, "Failed to publish scheduled posts", slog.Any("error", err))
+				continue
+			}
+			if published > 0 {
+				s.log.InfoContext(ctx, "Published scheduled posts", slog.Int("published", published))
+			}
+		}
+	}
+}
+
+// viewsWorker periodically flushes view counts buffered in redis to the post store.
+func (s *Server) viewsWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.viewsCfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Flush what is left, so views counted before shutdown are not lost
+			if _, err := s.viewService.Flush(context.WithoutCancel(ctx)); err != nil {
+				s.log.ErrorContext(ctx, "Failed to flush view counts", slog.Any("error", err))
+			}
+			return
+		case <-ticker.C:
+			flushed, err := s.viewService.Flush(ctx)
+			if err != nil {
+				s.log.ErrorContext(ctx, "Failed to flush view counts", slog.Any("error", err))
+				continue
+			}
+			if flushed > 0 {
+				s.log.DebugContext(ctx, "Flushed view counts", slog.Int("posts", flushed))
+			}
+		}
+	}
+}
+
 func handleShutdown(onShutdownSignal func()) <-chan struct{} {
 	shutdown := make(chan struct{})
 
@@ -112,7 +279,7 @@ func handleShutdown(onShutdownSignal func()) <-chan struct{} {
 func (s *Server) routes() {
 	s.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 	}))
 
@@ -121,16 +288,97 @@ func (s *Server) routes() {
 		httpSwagger.URL("http://localhost:8080/swagger/doc.json"), // The url pointing to API definition
 	))
 
+	s.router.Use(s.authenticate)
+
 	s.router.Get("/health", s.handleGetHealth)
-	s.router.Post("/api/v1/posts", s.CreatePostHandler)
-	s.router.Get("/api/v1/posts", s.GetPostListsHandler)
-	s.router.Get("/api/v1/posts/{id}", s.GetPostHandler)
 
-	s.router.Get("/api/v1/files/{id}", s.GetMarkdownHandler)
+	limits := s.rateLimitCfg
+	s.router.Group(func(r chi.Router) {
+		r.Use(s.rateLimit("auth", rateLimits{ip: limits.AuthIP, user: limits.AuthUser, apiKey: limits.AuthAPIKey}))
+
+		r.Post("/api/v1/auth/login", s.LoginHandler)
+		r.Post("/api/v1/auth/refresh", s.RefreshTokenHandler)
+		r.Get("/api/v1/auth/oidc/login", s.OIDCLoginHandler)
+		r.Get("/api/v1/auth/oidc/callback", s.OIDCCallbackHandler)
+		r.Post("/api/v1/users", s.RegisterUserHandler)
+	})
+
+	s.router.Group(func(r chi.Router) {
+		r.Use(s.rateLimit("read", rateLimits{ip: limits.ReadIP, user: limits.ReadUser, apiKey: limits.ReadAPIKey}))
+
+		r.Get("/api/v1/posts", s.GetPostListsHandler)
+		r.Get("/api/v1/posts/{id}", s.GetPostHandler)
+		r.Get("/api/v1/posts/{id}/html", s.GetPostHTMLHandler)
+		r.Get("/api/v1/posts/{id}/related", s.RelatedPostsHandler)
+		r.Get("/api/v1/posts/{id}/revisions", s.ListRevisionsHandler)
+		r.Get("/api/v1/posts/{id}/revisions/diff", s.DiffRevisionsHandler)
+		r.Get("/api/v1/posts/{id}/comments", s.ListCommentsHandler)
+
+		r.Get("/api/v1/files/{id}", s.GetMarkdownHandler)
+
+		r.Get("/api/v1/search", s.SearchHandler)
+
+		r.Get("/api/v1/users/{id}", s.GetUserHandler)
+		r.Get("/api/v1/users/{id}/avatar", s.GetAvatarHandler)
+		r.Get("/api/v1/users/{id}/posts", s.ListUserPostsHandler)
+
+		r.Get("/api/v1/tags", s.ListTagsHandler)
+		r.Get("/api/v1/tag-usage", s.ListTagUsageHandler)
+		r.Get("/api/v1/tags/{name}", s.GetTagHandler)
+		r.Get("/api/v1/tags/{name}/posts", s.ListTaggedPostsHandler)
+
+		r.Get("/api/v1/threads", s.ListThreadsHandler)
+		r.Get("/api/v1/thread/{id}/posts", s.ListPostsInThreadHandler)
+	})
+
+	// Routes for authenticated users, anonymous requests are rejected before they are counted
+	s.router.Group(func(r chi.Router) {
+		r.Use(s.requireAuth)
+		r.Use(s.rateLimit("write", rateLimits{user: limits.WriteUser, apiKey: limits.WriteAPIKey}))
+
+		r.Post("/api/v1/auth/logout", s.LogoutHandler)
+
+		r.Post("/api/v1/apikeys", s.CreateAPIKeyHandler)
+		r.Get("/api/v1/apikeys", s.ListAPIKeysHandler)
+		r.Delete("/api/v1/apikeys/{id}", s.RevokeAPIKeyHandler)
+		r.Get("/api/v1/admin/apikeys", s.ListAllAPIKeysHandler)
+		r.Delete("/api/v1/admin/apikeys/{id}", s.RevokeAnyAPIKeyHandler)
+		r.Get("/api/v1/admin/audit", s.ListAuditEventsHandler)
+		r.Patch("/api/v1/admin/tags/{name}", s.UpdateTagHandler)
+		r.Post("/api/v1/admin/tags/{name}/merge", s.MergeTagsHandler)
 
-	s.router.Get("/api/v1/tags", s.ListTagsHandler)
+		r.Post("/api/v1/posts", s.CreatePostHandler)
+		r.Get("/api/v1/posts/trash", s.ListDeletedPostsHandler)
+		r.Get("/api/v1/posts/drafts", s.ListDraftPostsHandler)
+		r.Put("/api/v1/posts/{id}", s.UpdatePostHandler)
+		r.Patch("/api/v1/posts/{id}", s.PatchPostHandler)
+		r.Delete("/api/v1/posts/{id}", s.DeletePostHandler)
+		r.Post("/api/v1/posts/{id}/restore", s.RestorePostHandler)
+		r.Post("/api/v1/posts/{id}/publish", s.PublishPostHandler)
+		r.Post("/api/v1/posts/{id}/unpublish", s.UnpublishPostHandler)
+		r.Post("/api/v1/posts/{id}/revisions/{rev}/restore", s.RestoreRevisionHandler)
+		r.Post("/api/v1/posts/{id}/authors", s.InviteAuthorHandler)
+		r.Post("/api/v1/posts/{id}/invitation/accept", s.AcceptInvitationHandler)
+		r.Post("/api/v1/posts/{id}/invitation/decline", s.DeclineInvitationHandler)
+		r.Get("/api/v1/invitations", s.ListInvitationsHandler)
+		r.Post("/api/v1/posts/{id}/comments", s.CreateCommentHandler)
 
-	s.router.Post("/api/v1/threads", s.CreateThreadHandler)
-	s.router.Get("/api/v1/threads", s.ListThreadsHandler)
-	s.router.Get("/api/v1/thread/{id}/posts", s.ListPostsInThreadHandler)
+		r.Put("/api/v1/comments/{id}", s.UpdateCommentHandler)
+		r.Delete("/api/v1/comments/{id}", s.DeleteCommentHandler)
+
+		r.Get("/api/v1/feed", s.FeedHandler)
+		r.Post("/api/v1/threads/{id}/follow", s.FollowThreadHandler)
+		r.Delete("/api/v1/threads/{id}/follow", s.UnfollowThreadHandler)
+		r.Post("/api/v1/users/{id}/follow", s.FollowUserHandler)
+		r.Delete("/api/v1/users/{id}/follow", s.UnfollowUserHandler)
+
+		r.Patch("/api/v1/users/{id}", s.UpdateUserHandler)
+		r.Put("/api/v1/users/{id}/avatar", s.UploadAvatarHandler)
+		r.Put("/api/v1/users/{id}/role", s.SetUserRoleHandler)
+
+		r.Post("/api/v1/threads", s.CreateThreadHandler)
+		r.Put("/api/v1/threads/{id}", s.UpdateThreadHandler)
+		r.Patch("/api/v1/threads/{id}", s.UpdateThreadHandler)
+		r.Delete("/api/v1/threads/{id}", s.DeleteThreadHandler)
+	})
 }
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+)
+
+// GetTagHandler handles the fetching of a tag.
+//
+// @Summary Retrieve a tag
+// @Description Fetch the tag with its display name, parent, children and aliases. The tag may be given by its
+// @Description name, one of its aliases or any spelling with the same slug, Go, go and GO are the same tag.
+// @Tags tags
+// @Produce json
+// @Param name path string true "Tag name or alias"
+// @Success 200 {object} models.Tag "Tag"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 404 {object} errors.ErrResponse "Tag not found"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/tags/{name} [get]
+func (s *Server) GetTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := r.PathValue("name")
+
+	if name == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "tag is empty",
+		})
+		return
+	}
+
+	tag, err := s.postService.GetTag(ctx, name)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch tag")
+		return
+	}
+
+	render.Render(w, r, tag)
+}
+
+// UpdateTagHandler handles the update of a tag.
+//
+// @Summary Update a tag
+// @Description Change the display name of the tag, place it under a parent tag or add and remove its aliases.
+// @Description Posts and threads tagged with an alias are tagged with the tag, posts tagged with children are
+// @Description listed under the parent too. Empty parent detaches the tag. Fields missing in the request are left
+// @Description as they are. Only admins can update tags.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param name path string true "Tag name or alias"
+// @Param data body models.UpdateTagRequest true "Tag update request"
+// @Security BearerAuth
+// @Success 200 {object} models.TagUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Tag or parent not found"
+// @Failure 409 {object} errors.ErrResponse "Parent is a descendant or alias is already taken"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/admin/tags/{name} [patch]
+func (s *Server) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := r.PathValue("name")
+
+	if name == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "tag is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.UpdateTagRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while updating tag", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	name, err = s.postService.UpdateTag(ctx, name, data)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to update tag")
+		return
+	}
+
+	render.Render(w, r, &models.TagUpdateResponse{
+		Status: http.StatusOK,
+		Name:   name,
+	})
+}
+
+// MergeTagsHandler handles merging one tag into another.
+//
+// @Summary Merge tags
+// @Description Merge the tag into another one in one transaction. Posts and threads tagged with the merged tag are
+// @Description tagged with the other tag instead, its children and aliases move to the other tag and its name
+// @Description becomes an alias of the other tag. Only admins can merge tags.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param name path string true "Name of the merged tag"
+// @Param data body models.MergeTagsRequest true "Tag the merged tag is merged into"
+// @Security BearerAuth
+// @Success 200 {object} models.TagMergeResponse
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Tag not found"
+// @Failure 409 {object} errors.ErrResponse "Tags are the same tag"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/admin/tags/{name}/merge [post]
+func (s *Server) MergeTagsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := r.PathValue("name")
+
+	if name == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "tag is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.MergeTagsRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while merging tags", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	resp, err := s.postService.MergeTags(ctx, name, data.Into)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to merge tags")
+		return
+	}
+
+	resp.Status = http.StatusOK
+	render.Render(w, r, resp)
+}
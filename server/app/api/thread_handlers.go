@@ -2,25 +2,28 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"github.com/go-chi/render"
 	"io"
 	"log/slog"
 	"ndb/server/app/models"
 	apierr "ndb/server/errors"
-	"ndb/server/services/posts"
 	"net/http"
+	"strconv"
 )
 
 // CreateThreadHandler handles the creation of a new thread
 // @Summary Create a new thread
-// @Description Create a new thread based on the provided request data
+// @Description Create a new thread based on the provided request data. Only editors and admins can create threads.
 // @Tags threads
 // @Accept  json
 // @Produce  json
 // @Param data body models.CreateThreadRequest true "Thread creation request"
+// @Security BearerAuth
 // @Success 200 {object} models.ThreadCreationResponse
 // @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
 // @Failure 500 {object} errors.ErrResponse "Internal server error"
 // @Router /api/v1/threads [post]
 func (s *Server) CreateThreadHandler(w http.ResponseWriter, r *http.Request) {
@@ -51,12 +54,7 @@ func (s *Server) CreateThreadHandler(w http.ResponseWriter, r *http.Request) {
 
 	threadID, err := s.postService.CreateThread(ctx, data)
 	if err != nil {
-		s.log.ErrorContext(
-			ctx,
-			"Failed to create thread",
-			slog.Any("error", err),
-		)
-		render.Render(w, r, apierr.ErrInternalServerError)
+		s.renderServiceError(w, r, err, "Failed to create thread")
 		return
 	}
 
@@ -66,6 +64,104 @@ func (s *Server) CreateThreadHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// UpdateThreadHandler handles the update of a thread
+// @Summary Update a thread
+// @Description Rename the thread, replace its description, replace, add or remove its tags or archive it.
+// @Description Fields missing in the request are left as they are. Archived threads accept no new posts.
+// @Description Only editors and admins can update threads.
+// @Tags threads
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Thread ID"
+// @Param data body models.UpdateThreadRequest true "Thread update request"
+// @Security BearerAuth
+// @Success 200 {object} models.ThreadUpdateResponse
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Thread not found"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/threads/{id} [put]
+// @Router /api/v1/threads/{id} [patch]
+func (s *Server) UpdateThreadHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	threadID := r.PathValue("id")
+
+	if threadID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "thread_id is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.UpdateThreadRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while updating thread", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	if err = s.postService.UpdateThread(ctx, threadID, data); err != nil {
+		s.renderServiceError(w, r, err, "Failed to update thread")
+		return
+	}
+
+	render.Render(w, r, &models.ThreadUpdateResponse{
+		Status:   http.StatusOK,
+		ThreadID: threadID,
+	})
+}
+
+// DeleteThreadHandler handles the deletion of a thread
+// @Summary Delete a thread
+// @Description Delete the thread. The policy decides what happens to its posts: refuse fails when the thread has
+// @Description posts outside of trash, cascade moves them to trash and move moves all of them to the target thread.
+// @Description Posts in trash of a deleted thread can't be restored. Only editors and admins can delete threads.
+// @Tags threads
+// @Produce  json
+// @Param id path string true "Thread ID"
+// @Param policy query string false "What happens to the posts of the thread" Enums(refuse, cascade, move) default(refuse)
+// @Param target query string false "ID of the open thread the posts are moved to, required by the move policy"
+// @Security BearerAuth
+// @Success 200 {object} models.ThreadDeletionResponse
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "Thread or target thread not found"
+// @Failure 409 {object} errors.ErrResponse "Thread has posts"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/threads/{id} [delete]
+func (s *Server) DeleteThreadHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	threadID := r.PathValue("id")
+
+	if threadID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "thread_id is empty",
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	resp, err := s.postService.DeleteThread(ctx, threadID, query.Get("policy"), query.Get("target"))
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to delete thread")
+		return
+	}
+
+	resp.Status = http.StatusOK
+	render.Render(w, r, resp)
+}
+
 // ListThreadsHandler fetches the list of threads
 // @Summary List all threads
 // @Description Fetches a list of all available threads
@@ -80,77 +176,164 @@ func (s *Server) ListThreadsHandler(w http.ResponseWriter, r *http.Request) {
 
 	threads, err := s.postService.ListThreads(ctx)
 	if err != nil {
-		if errors.Is(err, posts.ErrNotFound) {
-			s.log.ErrorContext(
-				ctx,
-				"No threads found",
-				slog.Any("error", err),
-			)
-			render.Render(w, r, apierr.ErrNotFound)
-			return
-		}
-
-		s.log.ErrorContext(
-			ctx,
-			"Failed to fetch list of threads.",
-			slog.Any("error", err),
-		)
-
-		render.Render(w, r, apierr.ErrInternalServerError)
+		s.renderServiceError(w, r, err, "Failed to fetch list of threads.")
 		return
 	}
 
 	render.Respond(w, r, threads)
 }
 
+const (
+	// defaultTrendDays is the number of days of activity trending tags are ranked by
+	defaultTrendDays = 7
+	maxTrendDays     = 90
+)
+
 // ListTagsHandler fetches the list of tags
 // @Summary List all tags
-// @Description Fetches a list of all available tags
+// @Description Fetches names of all available tags ordered by name. With with_counts=true the tags come with their
+// @Description usage instead, the response is the same as the one of /api/v1/tag-usage and takes its parameters.
 // @Tags tags
 // @Produce  json
-// @Success 200 {array} string "List of tags"
+// @Param with_counts query bool false "Return tags with their usage, see /api/v1/tag-usage"
+// @Success 200 {array} string "Names of the tags"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
 // @Success 404 {object} errors.ErrResponse "Not found error"
 // @Failure 500 {object} errors.ErrResponse "Internal server error"
 // @Router /api/v1/tags [get]
 func (s *Server) ListTagsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if withCounts, _ := strconv.ParseBool(r.URL.Query().Get("with_counts")); withCounts {
+		s.ListTagUsageHandler(w, r)
+		return
+	}
+
 	tags, err := s.postService.ListTags(ctx)
 	if err != nil {
-		if errors.Is(err, posts.ErrNotFound) {
-			s.log.ErrorContext(
-				ctx,
-				"No tags found",
-				slog.Any("error", err),
-			)
-			render.Render(w, r, apierr.ErrNotFound)
+		s.renderServiceError(w, r, err, "Failed to fetch list of tags")
+		return
+	}
+
+	render.Respond(w, r, tags)
+}
+
+// ListTagUsageHandler fetches tags with their usage
+// @Summary List tags with their usage
+// @Description Fetches tags with the number of their threads, published posts and their views, counting posts and
+// @Description threads tagged with their descendants, and the activity on their posts in the last days. Popular tags
+// @Description are ordered by their posts, trending ones by their trend, the weighted sum of recently updated posts,
+// @Description comments and reads, and tags without recent activity are left out. weight of each tag is relative to
+// @Description the most used tag of the list, for rendering a tag cloud.
+// @Tags tags
+// @Produce  json
+// @Param sort query string false "Sort order" Enums(popular, name, trending) default(popular)
+// @Param limit query int false "Number of tags" default(50)
+// @Param days query int false "Number of days of recent activity" default(7)
+// @Success 200 {array} models.TagUsage "Tags with their usage"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/tag-usage [get]
+func (s *Server) ListTagUsageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, err := parseLimit(r, defaultTagLimit, maxTagLimit)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse limit", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	days := defaultTrendDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > maxTrendDays {
+			render.Render(w, r, &apierr.ErrResponse{
+				Err:            err,
+				HTTPStatusCode: http.StatusBadRequest,
+				Message:        fmt.Sprintf("days must be between 1 and %d", maxTrendDays),
+			})
 			return
 		}
+	}
 
-		s.log.ErrorContext(
-			ctx,
-			"Failed to fetch list of threads.",
-			slog.Any("error", err),
-		)
+	usage, err := s.postService.ListTagUsage(ctx, r.URL.Query().Get("sort"), limit, days)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch tag usage")
+		return
+	}
+
+	render.Respond(w, r, usage)
+}
+
+// ListTaggedPostsHandler handles the fetching of posts with a tag.
+//
+// @Summary Retrieve posts with a tag
+// @Description Fetch a page of published posts tagged directly or through their thread, with the tag or one of its
+// @Description descendants. The tag may be given by one of its aliases. match of each post is post, thread or both.
+// @Description Pass next_cursor of the previous page as after to get the next one.
+// @Tags tags
+// @Produce json
+// @Param name path string true "Tag name or alias"
+// @Param limit query int false "Number of posts per page" default(10)
+// @Param sort query string false "Sort order" Enums(created, updated, views) default(created)
+// @Param after query string false "Cursor of the previous page"
+// @Success 200 {object} models.TaggedPostPage "Posts"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 404 {object} errors.ErrResponse "No posts found"
+// @Failure 500 {object} errors.ErrResponse "Internal server error"
+// @Router /api/v1/tags/{name}/posts [get]
+func (s *Server) ListTaggedPostsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tag := r.PathValue("name")
 
-		render.Render(w, r, apierr.ErrInternalServerError)
+	if tag == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "tag is empty",
+		})
 		return
 	}
 
-	render.Respond(w, r, tags)
+	params, err := parsePageParams(r)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse page parameters", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	page, err := s.postService.ListTaggedPosts(ctx, tag, params.Limit, params.Sort, params.After)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch tagged posts", slog.Any("tag", tag))
+		return
+	}
+
+	render.Render(w, r, page)
 }
 
 // ListPostsInThreadHandler handles the fetching of a posts for specified thread.
 //
 // @Summary Retrieve post data for specified thread
-// @Description Fetch post details from Neo4j. The response contains post details in JSON format followed by the image file.
+// @Description Fetch a page of published posts from the thread. Pass next_cursor of the previous page as after to get the next one.
 // @Tags threads
 // @Accept json
 // @Produce json
-// @Param id path string true "Post ID"
+// @Param id path string true "Thread ID"
+// @Param limit query int false "Number of posts per page" default(10)
+// @Param sort query string false "Sort order" Enums(created, updated, views) default(created)
+// @Param after query string false "Cursor of the previous page"
 // @Header 200 {string} Content-Type "application/json"
-// @Success 200 {object} []models.Post "Posts"
-// @Failure 400 {object} errors.ErrResponse "Invalid request or post not found"
+// @Success 200 {object} models.PostPage "Posts"
+// @Failure 400 {object} errors.ErrResponse "Invalid request"
+// @Failure 404 {object} errors.ErrResponse "No posts found"
 // @Failure 500 {object} errors.ErrResponse "Internal server error"
 // @Router /api/v1/thread/{id}/posts [get]
 func (s *Server) ListPostsInThreadHandler(w http.ResponseWriter, r *http.Request) {
@@ -161,33 +344,27 @@ func (s *Server) ListPostsInThreadHandler(w http.ResponseWriter, r *http.Request
 	if threadID == "" {
 		render.Render(w, r, &apierr.ErrResponse{
 			HTTPStatusCode: http.StatusBadRequest,
-			Message:        "post_id is empty",
+			Message:        "thread_id is empty",
 		})
 		return
 	}
 
-	postList, err := s.postService.ListPostInThread(ctx, threadID)
+	params, err := parsePageParams(r)
 	if err != nil {
-		if errors.Is(err, posts.ErrNotFound) {
-			s.log.ErrorContext(
-				ctx,
-				"No posts found",
-				slog.Any("error", err),
-			)
-			render.Render(w, r, apierr.ErrNotFound)
-			return
-		}
-
-		s.log.ErrorContext(
-			ctx,
-			"Failed to fetch posts in thread",
-			slog.Any("error", err),
-			slog.Any("thread_id", threadID),
-		)
+		s.log.ErrorContext(ctx, "Cannot parse page parameters", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
 
-		render.Render(w, r, apierr.ErrInternalServerError)
+	page, err := s.postService.ListPostInThread(ctx, threadID, params.Limit, params.Sort, params.After)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Failed to fetch posts in thread", slog.Any("thread_id", threadID))
 		return
 	}
 
-	render.Respond(w, r, postList)
+	render.Render(w, r, page)
 }
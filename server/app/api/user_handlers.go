@@ -0,0 +1,343 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"ndb/server/app/models"
+	apierr "ndb/server/errors"
+)
+
+// RegisterUserHandler handles the registration of a new user.
+//
+// @Summary Register a user
+// @Description Create a user account. Usernames and emails are unique and case-insensitive.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param data body models.RegisterUserRequest true "User registration request"
+// @Success 200 {object} models.UserCreationResponse
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 409 {object} errors.ErrResponse "Username or email already taken"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users [post]
+func (s *Server) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.RegisterUserRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while registering user", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	userID, err := s.userService.Register(ctx, data)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error registering user")
+		return
+	}
+
+	render.Render(w, r, &models.UserCreationResponse{
+		Status: http.StatusOK,
+		UserID: userID,
+	})
+}
+
+// GetUserHandler handles the fetching of a user profile.
+//
+// @Summary Retrieve user profile
+// @Description Fetch the public profile of a user with the number of published posts, their total views
+// and the threads they belong to.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.User "User profile with stats"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 404 {object} errors.ErrResponse "User not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id} [get]
+func (s *Server) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+
+	if userID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "user_id is empty",
+		})
+		return
+	}
+
+	user, err := s.userService.GetProfile(ctx, userID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error getting user")
+		return
+	}
+
+	render.Render(w, r, user)
+}
+
+// ListUserPostsHandler handles listing posts written by a user.
+//
+// @Summary List posts of a user
+// @Description Fetch a page of published posts written by the user. Pass next_cursor of the previous page as after to get the next one.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Param limit query int false "Number of posts per page, 10 by default, up to 100"
+// @Param sort query string false "Sort order: created (default), updated or views"
+// @Param after query string false "Cursor returned as next_cursor of the previous page"
+// @Success 200 {object} models.PostPage "Page of posts"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 404 {object} errors.ErrResponse "User not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id}/posts [get]
+func (s *Server) ListUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+
+	if userID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "user_id is empty",
+		})
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Cannot parse page parameters", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        err.Error(),
+		})
+		return
+	}
+
+	page, err := s.postService.ListUserPosts(ctx, userID, params.Limit, params.Sort, params.After)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error listing user posts", slog.Any("user_id", userID))
+		return
+	}
+
+	render.Render(w, r, page)
+}
+
+// UpdateUserHandler handles the update of a user profile.
+//
+// @Summary Update user profile
+// @Description Change the display name and/or bio of a user.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param data body models.UpdateUserRequest true "User update request"
+// @Security BearerAuth
+// @Success 200 {object} models.User "Updated user profile"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "User not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id} [patch]
+func (s *Server) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+
+	if userID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "user_id is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.UpdateUserRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while updating user", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	if err = s.userService.UpdateProfile(ctx, userID, data); err != nil {
+		s.renderServiceError(w, r, err, "Error updating user")
+		return
+	}
+
+	user, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error getting user")
+		return
+	}
+
+	render.Render(w, r, user)
+}
+
+// SetUserRoleHandler handles the change of a user role.
+//
+// @Summary Change user role
+// @Description Make the user an admin, editor, author or reader. Only admins can change roles.
+// The new role applies to access tokens issued after the change.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param data body models.SetRoleRequest true "Role change request"
+// @Security BearerAuth
+// @Success 200 {object} models.User "Updated user profile"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "User not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id}/role [put]
+func (s *Server) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+
+	if userID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "user_id is empty",
+		})
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading body", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	data := &models.SetRoleRequest{}
+	if err = json.Unmarshal(b, data); err != nil {
+		s.log.ErrorContext(ctx, "Failed to parse request while changing role", slog.Any("error", err))
+		render.Render(w, r, apierr.ErrBadRequest)
+		return
+	}
+
+	if err = s.userService.SetRole(ctx, userID, data.Role); err != nil {
+		s.renderServiceError(w, r, err, "Error changing role")
+		return
+	}
+
+	user, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error getting user")
+		return
+	}
+
+	render.Render(w, r, user)
+}
+
+// UploadAvatarHandler handles the upload of a user avatar.
+//
+// @Summary Upload user avatar
+// @Description Store a png, jpeg, gif or webp image of up to 2MB in S3 as the avatar of the user.
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "User ID"
+// @Param avatar formData file true "Avatar image"
+// @Security BearerAuth
+// @Success 200 {object} models.User "Updated user profile"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 401 {object} errors.ErrResponse "Unauthorized"
+// @Failure 403 {object} errors.ErrResponse "Permission denied"
+// @Failure 404 {object} errors.ErrResponse "User not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id}/avatar [put]
+func (s *Server) UploadAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+
+	if userID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "user_id is empty",
+		})
+		return
+	}
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		s.log.ErrorContext(ctx, "No avatar found in form", slog.Any("error", err))
+		render.Render(w, r, &apierr.ErrResponse{
+			Err:            fmt.Errorf("no avatar provided: %w", err),
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "No avatar provided",
+		})
+		return
+	}
+	defer file.Close()
+
+	if _, err = s.userService.UploadAvatar(ctx, userID, file); err != nil {
+		s.renderServiceError(w, r, err, "Error uploading avatar")
+		return
+	}
+
+	user, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error getting user")
+		return
+	}
+
+	render.Render(w, r, user)
+}
+
+// GetAvatarHandler handles the fetching of a user avatar.
+//
+// @Summary Retrieve user avatar
+// @Description Fetch the avatar image of a user from S3.
+// @Tags users
+// @Produce png,jpeg,gif
+// @Param id path string true "User ID"
+// @Success 200 {file} file "Avatar image"
+// @Failure 400 {object} errors.ErrResponse "Bad Request"
+// @Failure 404 {object} errors.ErrResponse "User or avatar not found"
+// @Failure 500 {object} errors.ErrResponse "Internal Server Error"
+// @Router /api/v1/users/{id}/avatar [get]
+func (s *Server) GetAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := r.PathValue("id")
+
+	if userID == "" {
+		render.Render(w, r, &apierr.ErrResponse{
+			HTTPStatusCode: http.StatusBadRequest,
+			Message:        "user_id is empty",
+		})
+		return
+	}
+
+	avatar, contentType, err := s.userService.GetAvatar(ctx, userID)
+	if err != nil {
+		s.renderServiceError(w, r, err, "Error getting avatar")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if _, err = w.Write(avatar); err != nil {
+		s.log.ErrorContext(ctx, "Error writing avatar to response", slog.Any("error", err))
+	}
+}
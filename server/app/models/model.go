@@ -1,26 +1,66 @@
 package models
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type CreatePostRequest struct {
-	Title  string `json:"title"`
-	UserID int64  `json:"user_id"`
-	Thread string `json:"thread"`
+	Title       string    `json:"title"`
+	UserID      string    `json:"user_id"`
+	Thread      string    `json:"thread"`
+	Draft       bool      `json:"draft"`
+	PublishAt   time.Time `json:"publish_at,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	CoverImage  string    `json:"cover_image,omitempty"`
+	PublishDate time.Time `json:"publish_date,omitempty"`
 }
 
 func (mr *CreatePostRequest) Bind(_ *http.Request) error {
 	return nil
 }
 
+type UpdatePostRequest struct {
+	Title       string    `json:"title"`
+	Thread      string    `json:"thread"`
+	UserID      string    `json:"user_id,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	CoverImage  string    `json:"cover_image,omitempty"`
+	PublishDate time.Time `json:"publish_date,omitempty"`
+	PublishAt   time.Time `json:"publish_at,omitempty"`
+}
+
+func (mr *UpdatePostRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
 type CreateThreadRequest struct {
-	Name string   `json:"name"`
-	Tags []string `json:"tags"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags"`
 }
 
 func (hr CreateThreadRequest) Bind(*http.Request) error {
 	return nil
 }
 
+// UpdateThreadRequest changes the fields present in the request, the others are left as they are.
+type UpdateThreadRequest struct {
+	Name        string  `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	// Tags replace all tags of the thread
+	Tags       []string `json:"tags,omitempty"`
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	Archived   *bool    `json:"archived,omitempty"`
+}
+
+func (hr UpdateThreadRequest) Bind(*http.Request) error {
+	return nil
+}
+
 type PostCreationResponse struct {
 	Status int    `json:"status"`
 	PostID string `json:"post_id"`
@@ -30,6 +70,25 @@ func (hr PostCreationResponse) Render(_ http.ResponseWriter, _ *http.Request) er
 	return nil
 }
 
+type PostUpdateResponse struct {
+	Status int    `json:"status"`
+	PostID string `json:"post_id"`
+}
+
+func (hr PostUpdateResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type PostDeletionResponse struct {
+	Status    int    `json:"status"`
+	PostID    string `json:"post_id"`
+	DeletedAt string `json:"deleted_at"`
+}
+
+func (hr PostDeletionResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
 type ThreadCreationResponse struct {
 	Status   int    `json:"status"`
 	ThreadID string `json:"thread_id"`
@@ -39,26 +98,462 @@ func (mr *ThreadCreationResponse) Bind(_ *http.Request) error {
 	return nil
 }
 
+type ThreadUpdateResponse struct {
+	Status   int    `json:"status"`
+	ThreadID string `json:"thread_id"`
+}
+
+func (hr ThreadUpdateResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type ThreadDeletionResponse struct {
+	Status    int    `json:"status"`
+	ThreadID  string `json:"thread_id"`
+	Policy    string `json:"policy"`
+	DeletedAt string `json:"deleted_at"`
+	// Posts is the number of posts moved to trash or to another thread
+	Posts int `json:"posts"`
+}
+
+func (hr ThreadDeletionResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
 type Post struct {
-	PostID      string `json:"post_id"`
+	PostID string `json:"post_id"`
+	// Authors are credited on the post in their order, the first one created it
+	Authors      []*Author `json:"authors"`
+	ThreadID     string    `json:"thread_id,omitempty"`
+	ThreadName   string    `json:"thread_name,omitempty"`
+	Title        string    `json:"title"`
+	ContentFile  string    `json:"content_file"`
+	UpdatedAt    string    `json:"date,omitempty"`
+	DeletedAt    string    `json:"deleted_at,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	PublishAt    string    `json:"publish_at,omitempty"`
+	ViewCount    int       `json:"view_count"`
+	CommentCount int       `json:"comment_count"`
+	Description  string    `json:"description,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	CoverImage   string    `json:"cover_image,omitempty"`
+	PublishDate  string    `json:"publish_date,omitempty"`
+}
+
+func (hr Post) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// Author is a user credited on a post.
+type Author struct {
 	UserID      string `json:"user_id"`
-	ThreadID    string `json:"thread_id,omitempty"`
-	Title       string `json:"title"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Role        string `json:"role"`
+}
+
+type InviteAuthorRequest struct {
+	UserID string `json:"user_id"`
+	// Role is author, editor or contributor, author by default
+	Role string `json:"role,omitempty"`
+}
+
+func (hr InviteAuthorRequest) Bind(*http.Request) error {
+	return nil
+}
+
+type Invitation struct {
+	PostID    string `json:"post_id"`
+	PostTitle string `json:"post_title"`
+	Role      string `json:"role"`
+	InvitedBy string `json:"invited_by"`
+	InvitedAt string `json:"invited_at"`
+}
+
+func (hr Invitation) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type FollowResponse struct {
+	Status int `json:"status"`
+	// Type is thread or user
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Following bool   `json:"following"`
+}
+
+func (hr FollowResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type Revision struct {
+	Number      int    `json:"revision"`
 	ContentFile string `json:"content_file"`
-	UpdatedAt   string `json:"date,omitempty"`
-	ViewCount   int    `json:"view_count"`
+	ContentHash string `json:"content_hash"`
+	AuthorID    string `json:"author_id"`
+	CreatedAt   string `json:"created_at"`
 }
 
-func (hr Post) Render(_ http.ResponseWriter, _ *http.Request) error {
+func (hr Revision) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type RevisionRestoreResponse struct {
+	Status   int    `json:"status"`
+	PostID   string `json:"post_id"`
+	Revision int    `json:"revision"`
+}
+
+func (hr RevisionRestoreResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type PostPage struct {
+	Posts      []*Post `json:"posts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func (hr PostPage) Render(_ http.ResponseWriter, _ *http.Request) error {
 	return nil
 }
 
 type Thread struct {
-	ThreadID string   `json:"thread_id"`
-	Name     string   `json:"name"`
-	Tags     []string `json:"tags"`
+	ThreadID    string   `json:"thread_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags"`
+	Archived    bool     `json:"archived,omitempty"`
 }
 
 func (hr Thread) Render(_ http.ResponseWriter, _ *http.Request) error {
 	return nil
 }
+
+type SearchResult struct {
+	Post    *Post   `json:"post"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet,omitempty"`
+}
+
+type SearchPage struct {
+	Results    []*SearchResult `json:"results"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+func (hr SearchPage) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// Tag is a canonical tag, name is its slug which posts and threads are tagged with. Aliases are other slugs
+// which lead to the tag, children are tags of narrower topics.
+type Tag struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name"`
+	Parent      string   `json:"parent,omitempty"`
+	Children    []string `json:"children"`
+	Aliases     []string `json:"aliases"`
+}
+
+func (hr Tag) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// TagUsage tells how much a tag is used, posts and threads tagged with its descendants count for it. Recent counts
+// are the posts updated, comments written and reads of the posts in the last days, trend is their weighted sum.
+// Weight is the number of posts of the tag, or its trend for trending tags, relative to the most used tag of
+// the list, from 0 to 1.
+type TagUsage struct {
+	Name           string  `json:"name"`
+	DisplayName    string  `json:"display_name"`
+	Threads        int     `json:"threads"`
+	Posts          int     `json:"posts"`
+	Views          int     `json:"views"`
+	RecentPosts    int     `json:"recent_posts"`
+	RecentComments int     `json:"recent_comments"`
+	RecentReads    int     `json:"recent_reads"`
+	Trend          float64 `json:"trend"`
+	Weight         float64 `json:"weight"`
+}
+
+// UpdateTagRequest changes the fields present in the request, the others are left as they are.
+type UpdateTagRequest struct {
+	DisplayName *string `json:"display_name,omitempty"`
+	// Parent places the tag under another tag, empty parent detaches it
+	Parent        *string  `json:"parent,omitempty"`
+	AddAliases    []string `json:"add_aliases,omitempty"`
+	RemoveAliases []string `json:"remove_aliases,omitempty"`
+}
+
+func (hr UpdateTagRequest) Bind(*http.Request) error {
+	return nil
+}
+
+type TagUpdateResponse struct {
+	Status int    `json:"status"`
+	Name   string `json:"name"`
+}
+
+func (hr TagUpdateResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// MergeTagsRequest names the tag the merged tag is merged into.
+type MergeTagsRequest struct {
+	Into string `json:"into"`
+}
+
+func (hr MergeTagsRequest) Bind(*http.Request) error {
+	return nil
+}
+
+// TagMergeResponse tells how many posts and threads were tagged with the merged tag, source, before they
+// were tagged with target.
+type TagMergeResponse struct {
+	Status  int    `json:"status"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	Posts   int    `json:"posts"`
+	Threads int    `json:"threads"`
+}
+
+func (hr TagMergeResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// TaggedPost is a post found by a tag, match is post when the post has the tag, thread when its thread has it
+// and both when they both have it.
+type TaggedPost struct {
+	Post  *Post  `json:"post"`
+	Match string `json:"match"`
+}
+
+type TaggedPostPage struct {
+	Posts      []*TaggedPost `json:"posts"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (hr TaggedPostPage) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// RelatedPost is a post recommended after another one. Reasons tell why it was chosen: same_author,
+// shared_tags, same_thread or co_read, when users who read one post read the other one too.
+type RelatedPost struct {
+	Post       *Post    `json:"post"`
+	Score      float64  `json:"score"`
+	Reasons    []string `json:"reasons"`
+	SharedTags []string `json:"shared_tags,omitempty"`
+	CoReaders  int      `json:"co_readers,omitempty"`
+}
+
+type CreateCommentRequest struct {
+	// UserID is the authenticated author, it is not read from the request body
+	UserID   string `json:"-"`
+	Body     string `json:"body"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+func (mr *CreateCommentRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body"`
+}
+
+func (mr *UpdateCommentRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type CommentCreationResponse struct {
+	Status    int    `json:"status"`
+	CommentID string `json:"comment_id"`
+}
+
+func (hr CommentCreationResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type CommentUpdateResponse struct {
+	Status    int    `json:"status"`
+	CommentID string `json:"comment_id"`
+}
+
+func (hr CommentUpdateResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type Comment struct {
+	CommentID string `json:"comment_id"`
+	PostID    string `json:"post_id"`
+	ParentID  string `json:"parent_id,omitempty"`
+	UserID    string `json:"user_id"`
+	Body      string `json:"body"`
+	Deleted   bool   `json:"deleted,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	// Replies are filled only when comments are listed as a tree
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+type CommentPage struct {
+	Comments   []*Comment `json:"comments"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+func (hr CommentPage) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type RegisterUserRequest struct {
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+}
+
+func (mr *RegisterUserRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type UpdateUserRequest struct {
+	DisplayName string `json:"display_name,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+}
+
+func (mr *UpdateUserRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type SetRoleRequest struct {
+	// Role is one of admin, editor, author or reader
+	Role string `json:"role"`
+}
+
+func (mr *SetRoleRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type UserCreationResponse struct {
+	Status int    `json:"status"`
+	UserID string `json:"user_id"`
+}
+
+func (hr UserCreationResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type User struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio,omitempty"`
+	AvatarKey   string `json:"avatar_key,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	// Stats are filled only on the profile page
+	Stats *UserStats `json:"stats,omitempty"`
+}
+
+type UserStats struct {
+	PostCount  int                   `json:"post_count"`
+	TotalViews int                   `json:"total_views"`
+	Threads    []*ThreadContribution `json:"threads"`
+}
+
+type ThreadContribution struct {
+	ThreadID  string `json:"thread_id"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
+
+func (hr User) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (mr *LoginRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (mr *RefreshTokenRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Scopes are any of posts:write, threads:write and logs:read
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is an optional RFC3339 date after which the key stops working
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+func (mr *CreateAPIKeyRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+type APIKeyCreationResponse struct {
+	Status int    `json:"status"`
+	KeyID  string `json:"key_id"`
+	// Key is shown only once, it can't be retrieved later
+	Key string `json:"key"`
+}
+
+func (hr APIKeyCreationResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type APIKey struct {
+	KeyID      string   `json:"key_id"`
+	UserID     string   `json:"user_id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	RevokedAt  string   `json:"revoked_at,omitempty"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int `json:"expires_in"`
+}
+
+func (hr TokenResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+type AuditEvent struct {
+	EventID    string `json:"event_id"`
+	OccurredAt string `json:"occurred_at"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	Action     string `json:"action"`
+	ActorID    string `json:"actor_id"`
+	ActorName  string `json:"actor_name"`
+	RequestID  string `json:"request_id,omitempty"`
+	// Before and After summarize the entity, Before is empty for created entities and After for removed ones
+	Before map[string]string `json:"before,omitempty"`
+	After  map[string]string `json:"after,omitempty"`
+}
+
+type AuditPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (hr AuditPage) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
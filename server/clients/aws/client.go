@@ -181,3 +181,24 @@ func (s *Client) Get(ctx context.Context, key string) (io.ReadCloser, error) {
 	)
 	return output.Body, nil
 }
+
+func (s *Client) Delete(ctx context.Context, key string) error {
+	_, err := s.baseClient.DeleteObject(ctx,
+		&s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	s.log.InfoContext(
+		ctx,
+		"Successfully deleted object",
+		slog.Any("key", key),
+		slog.Any("bucket", s.bucket),
+	)
+	return nil
+}
@@ -19,8 +19,76 @@ type Config struct {
 	S3         S3 `envPrefix:"S3_"`
 	Scylla     Scylla
 	HTTPServer HTTPServer
-	Neo4j      Neo4j `envPrefix:"NEO4J_"`
-	Redis      Redis `envPrefix:"REDIS_"`
+	Neo4j      Neo4j     `envPrefix:"NEO4J_"`
+	Redis      Redis     `envPrefix:"REDIS_"`
+	Posts      Posts     `envPrefix:"POSTS_"`
+	Views      Views     `envPrefix:"VIEWS_"`
+	Auth       Auth      `envPrefix:"AUTH_"`
+	OIDC       OIDC      `envPrefix:"OIDC_"`
+	RateLimit  RateLimit `envPrefix:"RATE_LIMIT_"`
+}
+
+// RateLimit holds the number of requests allowed per window for each route group and identity,
+// 0 disables the limit. Anonymous clients are identified by their IP.
+type RateLimit struct {
+	Enabled bool          `env:"ENABLED" envDefault:"true"`
+	Window  time.Duration `env:"WINDOW" envDefault:"1m"`
+
+	// Auth routes are login, token refresh and registration
+	AuthIP     int `env:"AUTH_IP" envDefault:"10"`
+	AuthUser   int `env:"AUTH_USER" envDefault:"10"`
+	AuthAPIKey int `env:"AUTH_API_KEY" envDefault:"10"`
+
+	ReadIP     int `env:"READ_IP" envDefault:"300"`
+	ReadUser   int `env:"READ_USER" envDefault:"600"`
+	ReadAPIKey int `env:"READ_API_KEY" envDefault:"600"`
+
+	WriteUser   int `env:"WRITE_USER" envDefault:"30"`
+	WriteAPIKey int `env:"WRITE_API_KEY" envDefault:"120"`
+}
+
+type Auth struct {
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	// DefaultRole is given to users on registration, except Admins which become admins
+	DefaultRole string   `env:"DEFAULT_ROLE" envDefault:"author"`
+	Admins      []string `env:"ADMINS" envSeparator:","`
+	// PasswordLogin allows registration and login with local passwords, it can be turned off when users
+	// sign in through OIDC
+	PasswordLogin bool `env:"PASSWORD_LOGIN" envDefault:"true"`
+}
+
+// OIDC configures sign in through an OpenID Connect provider with the authorization code flow and PKCE.
+// It is disabled when no issuer is set.
+type OIDC struct {
+	Issuer       string `env:"ISSUER"`
+	ClientID     string `env:"CLIENT_ID"`
+	ClientSecret string `env:"CLIENT_SECRET"`
+	// RedirectURL is the page the provider sends users back to, it passes code and state to the callback endpoint
+	RedirectURL string `env:"REDIRECT_URL"`
+	// FrontendURL is the page the callback redirects to at the end of the login, with the tokens or the error
+	// in the URL fragment
+	FrontendURL string   `env:"FRONTEND_URL"`
+	Scopes      []string `env:"SCOPES" envSeparator:"," envDefault:"openid,profile,email"`
+	// RoleClaim names the claim holding groups or roles of the user, a string or a list of strings
+	RoleClaim string `env:"ROLE_CLAIM" envDefault:"groups"`
+	// Roles maps values of the role claim to roles, e.g. "blog-admins:admin,blog-editors:editor"
+	Roles map[string]string `env:"ROLES"`
+	// LoginTimeout is how long a started login waits for the callback
+	LoginTimeout time.Duration `env:"LOGIN_TIMEOUT" envDefault:"10m"`
+}
+
+type Views struct {
+	FlushInterval time.Duration `env:"FLUSH_INTERVAL" envDefault:"30s"`
+	DedupWindow   time.Duration `env:"DEDUP_WINDOW" envDefault:"30m"`
+}
+
+type Posts struct {
+	TrashRetention  time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+	PurgeInterval   time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`
+	PublishInterval time.Duration `env:"PUBLISH_INTERVAL" envDefault:"1m"`
+	// TagUsageCacheTTL is how long tags with their usage are cached, 0 disables the cache
+	TagUsageCacheTTL time.Duration `env:"TAG_USAGE_CACHE_TTL" envDefault:"1m"`
 }
 
 type Redis struct {
@@ -55,4 +123,7 @@ type HTTPServer struct {
 	Port         int           `env:"PORT" envDefault:"8080"`
 	ReadTimeout  time.Duration `env:"HTTP_SERVER_READ_TIMEOUT" envDefault:"1s"`
 	WriteTimeout time.Duration `env:"HTTP_SERVER_WRITE_TIMEOUT" envDefault:"2s"`
+	// TrustedProxies lists addresses or CIDR ranges of proxies, e.g. the server rendering the frontend, whose
+	// X-Forwarded-For header identifies the client
+	TrustedProxies []string `env:"HTTP_SERVER_TRUSTED_PROXIES" envSeparator:","`
 }
@@ -22,5 +22,9 @@ func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
 var (
 	ErrNotFound            = &ErrResponse{HTTPStatusCode: 404, Message: "Resource not found."}
 	ErrBadRequest          = &ErrResponse{HTTPStatusCode: 400, Message: "Bad request"}
+	ErrUnauthorized        = &ErrResponse{HTTPStatusCode: 401, Message: "Authentication required."}
+	ErrForbidden           = &ErrResponse{HTTPStatusCode: 403, Message: "Permission denied."}
+	ErrConflict            = &ErrResponse{HTTPStatusCode: 409, Message: "Resource was modified concurrently."}
+	ErrTooManyRequests     = &ErrResponse{HTTPStatusCode: 429, Message: "Too many requests."}
 	ErrInternalServerError = &ErrResponse{HTTPStatusCode: 500, Message: "Internal Server Error"}
 )
@@ -16,6 +16,10 @@ import (
 	logrepo "ndb/server/repositories/log"
 )
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token issued by /api/v1/auth/login or an API key, sent as "Bearer <token>".
 func main() {
 	ctx := context.Background()
 	defaultLogger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
@@ -57,7 +61,7 @@ func main() {
 		panic(err)
 	}
 
-	server, err := api.NewServer(ctx, log, cfg)
+	server, err := api.NewServer(ctx, log, cfg, store)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,121 @@
+package logrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	insertAuditEventQuery = `INSERT INTO audit_events
+        (entity_id, event_id, entity_type, action, actor_id, actor_name, request_id, before, after)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	auditEventColumns = `entity_id, event_id, entity_type, action, actor_id, actor_name, request_id, before, after`
+)
+
+// AuditEvent records a change of an entity. Before and After summarize the entity, either of them is empty
+// when the entity was created or removed.
+type AuditEvent struct {
+	EventID    gocql.UUID
+	EntityType string
+	EntityID   string
+	Action     string
+	ActorID    string
+	ActorName  string
+	RequestID  string
+	Before     map[string]string
+	After      map[string]string
+}
+
+// OccurredAt returns the time encoded in the time based ID of the event.
+func (e *AuditEvent) OccurredAt() time.Time {
+	return e.EventID.Time().UTC()
+}
+
+// AuditQuery selects events of the entity, of the actor or, when both are set, of the actor on the entity.
+// PageState continues a previous query.
+type AuditQuery struct {
+	EntityID  string
+	ActorID   string
+	Limit     int
+	PageState []byte
+}
+
+// InsertAuditEvent adds the event to the batch, it is written together with logs on the next flush.
+func (c *Store) InsertAuditEvent(ctx context.Context, event *AuditEvent) {
+	c.Insert(
+		ctx,
+		insertAuditEventQuery,
+		event.EntityID,
+		event.EventID,
+		event.EntityType,
+		event.Action,
+		event.ActorID,
+		event.ActorName,
+		event.RequestID,
+		event.Before,
+		event.After,
+	)
+}
+
+// ListAuditEvents returns a page of events matching the query, the newest first, and the state to fetch the next
+// page with. The state is nil on the last page. Events not flushed yet are not included.
+func (c *Store) ListAuditEvents(ctx context.Context, query *AuditQuery) ([]*AuditEvent, []byte, error) {
+	var (
+		stmt   string
+		values []any
+	)
+	switch {
+	case query.EntityID != "" && query.ActorID != "":
+		stmt = `SELECT ` + auditEventColumns + ` FROM audit_events WHERE entity_id = ? AND actor_id = ? ALLOW FILTERING`
+		values = []any{query.EntityID, query.ActorID}
+	case query.EntityID != "":
+		stmt = `SELECT ` + auditEventColumns + ` FROM audit_events WHERE entity_id = ?`
+		values = []any{query.EntityID}
+	case query.ActorID != "":
+		stmt = `SELECT ` + auditEventColumns + ` FROM audit_events_by_actor WHERE actor_id = ?`
+		values = []any{query.ActorID}
+	default:
+		return nil, nil, fmt.Errorf("entity or actor is required")
+	}
+
+	iter := c.session.Query(stmt, values...).
+		WithContext(ctx).
+		PageSize(query.Limit).
+		PageState(query.PageState).
+		Iter()
+	pageState := iter.PageState()
+
+	var events []*AuditEvent
+	for {
+		event := &AuditEvent{}
+		if !iter.Scan(
+			&event.EntityID,
+			&event.EventID,
+			&event.EntityType,
+			&event.Action,
+			&event.ActorID,
+			&event.ActorName,
+			&event.RequestID,
+			&event.Before,
+			&event.After,
+		) {
+			break
+		}
+		events = append(events, event)
+	}
+
+	if err := iter.Close(); err != nil {
+		c.log.ErrorContext(ctx, "Failed to list audit events", slog.Any("error", err))
+		return nil, nil, err
+	}
+
+	if len(pageState) == 0 {
+		pageState = nil
+	}
+	return events, pageState, nil
+}
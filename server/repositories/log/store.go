@@ -93,8 +93,12 @@ func (c *Store) Insert(ctx context.Context, query string, values ...interface{})
 	c.log.DebugContext(ctx, "Add new query to batch", "query", query, "values", values)
 	c.batch.Query(query, values...)
 	if len(c.batch.Entries) >= c.batchSize {
-		// If batch size reaches limit, flush the batch.
-		c.flushCh <- struct{}{}
+		// If batch size reaches limit, flush the batch. The worker may be already waiting for the lock to flush it,
+		// so don't block when it doesn't take the signal.
+		select {
+		case c.flushCh <- struct{}{}:
+		default:
+		}
 	}
 }
 
@@ -0,0 +1,262 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// CreateAPIKey stores the key and makes the user its owner.
+func (s *Store) CreateAPIKey(ctx context.Context, key *model.APIKey) (string, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	key.KeyID = uuid.New().String()
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User {userID: $userID})
+            CREATE (u)-[:OWNS]->(k:APIKey {
+                keyID: $id,
+                name: $name,
+                hash: $hash,
+                scopes: $scopes,
+                createdAt: $createdAt,
+                expiresAt: $expiresAt
+            })
+            RETURN k.keyID`,
+			map[string]any{
+				"id":        key.KeyID,
+				"userID":    key.UserID,
+				"name":      key.Name,
+				"hash":      key.Hash,
+				"scopes":    key.Scopes,
+				"createdAt": key.CreatedAt,
+				"expiresAt": nullable(key.ExpiresAt),
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to create API key", slog.Any("error", err), slog.Any("user_id", key.UserID))
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: user %s", ErrNotFound, key.UserID)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.log.InfoContext(ctx, "API key created", slog.Any("key_id", key.KeyID), slog.Any("user_id", key.UserID))
+	return key.KeyID, nil
+}
+
+// GetAPIKeyByHash returns the key with the given hash together with its owner.
+func (s *Store) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, *model.User, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	type keyWithOwner struct {
+		key   *model.APIKey
+		owner *model.User
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User)-[:OWNS]->(k:APIKey {hash: $hash})
+            RETURN k, u`,
+			map[string]any{
+				"hash": hash,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: API key", ErrNotFound)
+		}
+
+		record := res.Record()
+		keyNode := record.Values[0].(neo4j.Node)
+		userNode := record.Values[1].(neo4j.Node)
+		owner := mapToUser(&userNode)
+		return &keyWithOwner{key: mapToAPIKey(&keyNode, owner.UserID), owner: owner}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := result.(*keyWithOwner)
+	return found.key, found.owner, nil
+}
+
+// ListAPIKeys returns the keys of the user, or keys of all users when userID is empty, the newest first.
+func (s *Store) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User)-[:OWNS]->(k:APIKey)
+            WHERE $userID = '' OR u.userID = $userID
+            RETURN k, u.userID
+            ORDER BY k.createdAt DESC`,
+			map[string]any{
+				"userID": userID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []*model.APIKey
+		for res.Next(ctx) {
+			record := res.Record()
+			node := record.Values[0].(neo4j.Node)
+			keys = append(keys, mapToAPIKey(&node, record.Values[1].(string)))
+		}
+
+		return keys, res.Err()
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to list API keys", slog.Any("error", err), slog.Any("user_id", userID))
+		return nil, err
+	}
+
+	return result.([]*model.APIKey), nil
+}
+
+// GetAPIKey returns the key with the given ID.
+func (s *Store) GetAPIKey(ctx context.Context, keyID string) (*model.APIKey, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User)-[:OWNS]->(k:APIKey {keyID: $id})
+            RETURN k, u.userID`,
+			map[string]any{
+				"id": keyID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: API key %s", ErrNotFound, keyID)
+		}
+
+		record := res.Record()
+		node := record.Values[0].(neo4j.Node)
+		return mapToAPIKey(&node, record.Values[1].(string)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*model.APIKey), nil
+}
+
+// RevokeAPIKey marks the key revoked. Revoked keys are kept, so it stays visible who used them.
+func (s *Store) RevokeAPIKey(ctx context.Context, keyID, revokedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (k:APIKey {keyID: $id})
+            WHERE k.revokedAt IS NULL
+            SET k.revokedAt = $revokedAt
+            RETURN k.keyID`,
+			map[string]any{
+				"id":        keyID,
+				"revokedAt": revokedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to revoke API key", slog.Any("error", err), slog.Any("key_id", keyID))
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: active API key %s", ErrNotFound, keyID)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log.InfoContext(ctx, "API key revoked", slog.Any("key_id", keyID))
+	return nil
+}
+
+// TouchAPIKey records when the key was last used.
+func (s *Store) TouchAPIKey(ctx context.Context, keyID, usedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(
+			ctx,
+			`MATCH (k:APIKey {keyID: $id})
+            SET k.lastUsedAt = $usedAt`,
+			map[string]any{
+				"id":     keyID,
+				"usedAt": usedAt,
+			},
+		)
+		return nil, err
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to update API key usage", slog.Any("error", err), slog.Any("key_id", keyID))
+	}
+
+	return err
+}
+
+func mapToAPIKey(node *neo4j.Node, userID string) *model.APIKey {
+	key := model.APIKey{
+		KeyID:     node.Props["keyID"].(string),
+		UserID:    userID,
+		Name:      node.Props["name"].(string),
+		Hash:      node.Props["hash"].(string),
+		CreatedAt: node.Props["createdAt"].(string),
+	}
+	if scopes, ok := node.Props["scopes"].([]any); ok {
+		for _, scope := range scopes {
+			key.Scopes = append(key.Scopes, fmt.Sprint(scope))
+		}
+	}
+	if expiresAt, ok := node.Props["expiresAt"].(string); ok {
+		key.ExpiresAt = expiresAt
+	}
+	if lastUsedAt, ok := node.Props["lastUsedAt"].(string); ok {
+		key.LastUsedAt = lastUsedAt
+	}
+	if revokedAt, ok := node.Props["revokedAt"].(string); ok {
+		key.RevokedAt = revokedAt
+	}
+	return &key
+}
@@ -0,0 +1,313 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// GetAuthors returns authors of the posts keyed by post ID, in their order. Relationships created before
+// co-authors were supported have no order and role, they belong to the only author of the post. Posts created
+// before users were stored have only the ID of their author, like in GetPostAuthors.
+func (s *Store) GetAuthors(ctx context.Context, postIDs []string) (map[string][]*model.Author, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post)
+            WHERE p.postID IN $ids
+            OPTIONAL MATCH (u:User)-[a:AUTHORED]->(p)
+            RETURN p.postID, p.userID, u, coalesce(a.role, 'author') AS role, coalesce(a.order, 0) AS order
+            ORDER BY p.postID, order`,
+			map[string]any{
+				"ids": postIDs,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		authors := make(map[string][]*model.Author, len(postIDs))
+		for res.Next(ctx) {
+			record := res.Record()
+			postID := record.Values[0].(string)
+			node, ok := record.Values[2].(neo4j.Node)
+			if !ok {
+				if userID, ok := record.Values[1].(string); ok {
+					authors[postID] = append(authors[postID], &model.Author{
+						UserID: userID,
+						Role:   model.AuthorRoleAuthor,
+					})
+				}
+				continue
+			}
+
+			user := mapToUser(&node)
+			authors[postID] = append(authors[postID], &model.Author{
+				UserID:      user.UserID,
+				Username:    user.Username,
+				DisplayName: user.DisplayName,
+				Role:        model.AuthorRole(record.Values[3].(string)),
+				Order:       int(record.Values[4].(int64)),
+			})
+		}
+
+		return authors, res.Err()
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get authors", slog.Any("error", err))
+		return nil, err
+	}
+
+	return result.(map[string][]*model.Author), nil
+}
+
+// GetPostAuthors returns authors of the post, whatever its status is.
+func (s *Store) GetPostAuthors(ctx context.Context, postID string) ([]*model.Author, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})
+            OPTIONAL MATCH (u:User)-[a:AUTHORED]->(p)
+            WITH p, u, a
+            ORDER BY coalesce(a.order, 0)
+            RETURN p.userID,
+                   collect(CASE WHEN u IS NULL THEN null
+                                ELSE {userID: u.userID, username: u.username, displayName: u.displayName,
+                                      role: coalesce(a.role, 'author'), order: coalesce(a.order, 0)} END)`,
+			map[string]any{
+				"id": postID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
+
+		record := res.Record()
+		var authors []*model.Author
+		for _, value := range record.Values[1].([]any) {
+			author := value.(map[string]any)
+			authors = append(authors, &model.Author{
+				UserID:      author["userID"].(string),
+				Username:    author["username"].(string),
+				DisplayName: author["displayName"].(string),
+				Role:        model.AuthorRole(author["role"].(string)),
+				Order:       int(author["order"].(int64)),
+			})
+		}
+
+		// Posts created before users were stored have only the ID of their author
+		if len(authors) == 0 {
+			authors = append(authors, &model.Author{
+				UserID: fmt.Sprint(record.Values[0]),
+				Role:   model.AuthorRoleAuthor,
+			})
+		}
+
+		return authors, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*model.Author), nil
+}
+
+// InviteAuthor invites the user to become a co-author of the post, an earlier invitation of the user is replaced.
+// It fails with ErrNotFound when the post or the user doesn't exist and with ErrConflict when the user is
+// already an author of the post.
+func (s *Store) InviteAuthor(ctx context.Context, invitation *model.Invitation) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	params := map[string]any{
+		"postID":    invitation.PostID,
+		"userID":    invitation.UserID,
+		"role":      string(invitation.Role),
+		"invitedBy": invitation.InvitedBy,
+		"invitedAt": invitation.InvitedAt,
+	}
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $postID})
+            WHERE p.status <> 'deleted'
+            MATCH (u:User {userID: $userID})
+            RETURN EXISTS { (u)-[:AUTHORED]->(p) } AS author`,
+			params,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: post %s or user %s", ErrNotFound, invitation.PostID, invitation.UserID)
+		}
+		if res.Record().Values[0].(bool) {
+			return nil, fmt.Errorf("%w: user %s is already an author", ErrConflict, invitation.UserID)
+		}
+
+		_, err = tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $postID})
+            MATCH (u:User {userID: $userID})
+            MERGE (u)-[i:INVITED_TO]->(p)
+            SET i.role = $role,
+                i.invitedBy = $invitedBy,
+                i.invitedAt = $invitedAt`,
+			params,
+		)
+		return nil, err
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to invite author",
+			slog.Any("error", err),
+			slog.Any("post_id", invitation.PostID),
+			slog.Any("user_id", invitation.UserID),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// AcceptInvitation makes the invited user the last author of the post, with the role from the invitation.
+func (s *Store) AcceptInvitation(ctx context.Context, postID, userID, acceptedAt string) (model.AuthorRole, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User {userID: $userID})-[i:INVITED_TO]->(p:Post {postID: $postID})
+            WHERE p.status <> 'deleted'
+            OPTIONAL MATCH (:User)-[a:AUTHORED]->(p)
+            WITH u, i, p, i.role AS role, max(coalesce(a.order, 0)) AS last
+            CREATE (u)-[:AUTHORED {order: last + 1, role: role, since: $acceptedAt}]->(p)
+            DELETE i
+            RETURN role`,
+			map[string]any{
+				"postID":     postID,
+				"userID":     userID,
+				"acceptedAt": acceptedAt,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: invitation of user %s to post %s", ErrNotFound, userID, postID)
+		}
+
+		return model.AuthorRole(res.Record().Values[0].(string)), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.log.InfoContext(ctx, "Co-author invitation accepted", slog.Any("post_id", postID), slog.Any("user_id", userID))
+	return result.(model.AuthorRole), nil
+}
+
+// DeclineInvitation removes the invitation of the user to the post.
+func (s *Store) DeclineInvitation(ctx context.Context, postID, userID string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (:User {userID: $userID})-[i:INVITED_TO]->(:Post {postID: $postID})
+            DELETE i
+            RETURN count(i) AS declined`,
+			map[string]any{
+				"postID": postID,
+				"userID": userID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := res.Single(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if record.Values[0].(int64) == 0 {
+			return nil, fmt.Errorf("%w: invitation of user %s to post %s", ErrNotFound, userID, postID)
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// ListInvitations returns pending invitations of the user to posts which are not deleted, the newest first.
+func (s *Store) ListInvitations(ctx context.Context, userID string) ([]*model.Invitation, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (:User {userID: $userID})-[i:INVITED_TO]->(p:Post)
+            WHERE p.status <> 'deleted'
+            RETURN p.postID, p.title, i.role, i.invitedBy, i.invitedAt
+            ORDER BY i.invitedAt DESC`,
+			map[string]any{
+				"userID": userID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations := []*model.Invitation{}
+		for res.Next(ctx) {
+			record := res.Record()
+			invitations = append(invitations, &model.Invitation{
+				PostID:    record.Values[0].(string),
+				PostTitle: record.Values[1].(string),
+				UserID:    userID,
+				Role:      model.AuthorRole(record.Values[2].(string)),
+				InvitedBy: record.Values[3].(string),
+				InvitedAt: record.Values[4].(string),
+			})
+		}
+
+		return invitations, res.Err()
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to list invitations", slog.Any("error", err), slog.Any("user_id", userID))
+		return nil, err
+	}
+
+	return result.([]*model.Invitation), nil
+}
@@ -0,0 +1,312 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// CreateComment adds the comment to the published post, as a reply when the comment has a parent.
+// It fails with ErrNotFound when the post or the parent comment on the same post doesn't exist.
+func (s *Store) CreateComment(ctx context.Context, comment *model.Comment) (string, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	comment.CommentID = uuid.New().String()
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $postID})
+            WHERE p.status = 'published'
+            OPTIONAL MATCH (parent:Comment {commentID: $parentID})-[:ON]->(p)
+            WITH p, parent
+            WHERE $parentID = '' OR parent IS NOT NULL
+            CREATE (c:Comment {
+                commentID: $id,
+                userID: $userID,
+                body: $body,
+                createdAt: $createdAt,
+                updatedAt: $updatedAt
+            })-[:ON]->(p)
+            FOREACH (ignored IN CASE WHEN parent IS NULL THEN [] ELSE [1] END | CREATE (c)-[:REPLY_TO]->(parent))
+            SET p.commentCount = coalesce(p.commentCount, 0) + 1
+            RETURN c.commentID`,
+			map[string]any{
+				"id":        comment.CommentID,
+				"postID":    comment.PostID,
+				"parentID":  comment.ParentID,
+				"userID":    comment.UserID,
+				"body":      comment.Body,
+				"createdAt": comment.CreatedAt,
+				"updatedAt": comment.UpdatedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to create comment",
+				slog.Any("error", err),
+				slog.Any("post_id", comment.PostID),
+			)
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if comment.ParentID != "" {
+				return nil, fmt.Errorf("%w: post %s or comment %s", ErrNotFound, comment.PostID, comment.ParentID)
+			}
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, comment.PostID)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.log.InfoContext(
+		ctx,
+		"Comment created successfully",
+		slog.Any("post_id", comment.PostID),
+		slog.Any("comment_id", comment.CommentID),
+	)
+	return comment.CommentID, nil
+}
+
+// ListComments returns a page of comments on the post, the oldest first. With rootsOnly replies are left out.
+func (s *Store) ListComments(
+	ctx context.Context,
+	postID string,
+	rootsOnly bool,
+	page *model.PageQuery,
+) ([]*model.Comment, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	params := map[string]any{
+		"postID":    postID,
+		"rootsOnly": rootsOnly,
+		"limit":     page.Limit + 1,
+		"afterKey":  nil,
+		"afterID":   nil,
+	}
+	if page.After != nil {
+		params["afterKey"] = page.After.Key
+		params["afterID"] = page.After.ID
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (c:Comment)-[:ON]->(:Post {postID: $postID})
+            WHERE (NOT $rootsOnly OR NOT (c)-[:REPLY_TO]->(:Comment))
+              AND ($afterID IS NULL OR c.createdAt > $afterKey OR (c.createdAt = $afterKey AND c.commentID > $afterID))
+            OPTIONAL MATCH (c)-[:REPLY_TO]->(parent:Comment)
+            RETURN c, parent.commentID AS parent_id
+            ORDER BY c.createdAt, c.commentID
+            LIMIT $limit`,
+			params,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return collectComments(ctx, res, postID)
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to list comments", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	return result.([]*model.Comment), nil
+}
+
+// GetReplies returns all direct and nested replies to the comments, the oldest first.
+func (s *Store) GetReplies(ctx context.Context, postID string, commentIDs []string) ([]*model.Comment, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (root:Comment)
+            WHERE root.commentID IN $ids
+            MATCH (c:Comment)-[:REPLY_TO*1..]->(root)
+            WITH DISTINCT c
+            MATCH (c)-[:REPLY_TO]->(parent:Comment)
+            RETURN c, parent.commentID AS parent_id
+            ORDER BY c.createdAt, c.commentID`,
+			map[string]any{
+				"ids": commentIDs,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return collectComments(ctx, res, postID)
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get replies", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	return result.([]*model.Comment), nil
+}
+
+// GetComment returns the comment with the ID of the post it is on.
+func (s *Store) GetComment(ctx context.Context, commentID string) (*model.Comment, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (c:Comment {commentID: $id})-[:ON]->(p:Post)
+            OPTIONAL MATCH (c)-[:REPLY_TO]->(parent:Comment)
+            RETURN c, parent.commentID AS parent_id, p.postID AS post_id`,
+			map[string]any{
+				"id": commentID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: comment %s", ErrNotFound, commentID)
+		}
+
+		record := res.Record()
+		node := record.Values[0].(neo4j.Node)
+		comment := mapToComment(&node)
+		if parentID, ok := record.Values[1].(string); ok {
+			comment.ParentID = parentID
+		}
+		comment.PostID = record.Values[2].(string)
+		return comment, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*model.Comment), nil
+}
+
+// UpdateComment replaces the body of a comment which is not deleted.
+func (s *Store) UpdateComment(ctx context.Context, commentID, body, updatedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (c:Comment {commentID: $id})
+            WHERE c.deletedAt IS NULL
+            SET c.body = $body,
+                c.updatedAt = $updatedAt
+            RETURN c.commentID`,
+			map[string]any{
+				"id":        commentID,
+				"body":      body,
+				"updatedAt": updatedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to update comment",
+				slog.Any("error", err),
+				slog.Any("comment_id", commentID),
+			)
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: comment %s", ErrNotFound, commentID)
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// DeleteComment removes the body of the comment and marks it deleted. The node is kept, so replies to it
+// stay in place.
+func (s *Store) DeleteComment(ctx context.Context, commentID, deletedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (c:Comment {commentID: $id})-[:ON]->(p:Post)
+            WHERE c.deletedAt IS NULL
+            SET c.body = '',
+                c.deletedAt = $deletedAt,
+                c.updatedAt = $deletedAt,
+                p.commentCount = coalesce(p.commentCount, 1) - 1
+            RETURN c.commentID`,
+			map[string]any{
+				"id":        commentID,
+				"deletedAt": deletedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to delete comment",
+				slog.Any("error", err),
+				slog.Any("comment_id", commentID),
+			)
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: comment %s", ErrNotFound, commentID)
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+func collectComments(ctx context.Context, res neo4j.ResultWithContext, postID string) ([]*model.Comment, error) {
+	var comments []*model.Comment
+	for res.Next(ctx) {
+		record := res.Record()
+		node := record.Values[0].(neo4j.Node)
+		comment := mapToComment(&node)
+		comment.PostID = postID
+		if parentID, ok := record.Values[1].(string); ok {
+			comment.ParentID = parentID
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, res.Err()
+}
+
+func mapToComment(node *neo4j.Node) *model.Comment {
+	comment := model.Comment{
+		CommentID: node.Props["commentID"].(string),
+		UserID:    node.Props["userID"].(string),
+		Body:      node.Props["body"].(string),
+		CreatedAt: node.Props["createdAt"].(string),
+		UpdatedAt: node.Props["updatedAt"].(string),
+	}
+	if deletedAt, ok := node.Props["deletedAt"].(string); ok {
+		comment.DeletedAt = deletedAt
+	}
+	return &comment
+}
@@ -0,0 +1,109 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// followMatches finds the followed node by its ID, labels and properties cannot be parametrized.
+var followMatches = map[model.FollowTarget]string{
+	model.FollowThread: `MATCH (target:Thread {threadID: $targetID})`,
+	model.FollowUser:   `MATCH (target:User {userID: $targetID})`,
+}
+
+// Follow makes the user follow the thread or the author, following it again keeps the original date.
+// It fails with ErrNotFound when the user or the followed node doesn't exist or the thread is deleted.
+func (s *Store) Follow(ctx context.Context, userID string, target model.FollowTarget, targetID, since string) error {
+	match, ok := followMatches[target]
+	if !ok {
+		return fmt.Errorf("unknown follow target: %s", target)
+	}
+
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			match+`
+            WHERE target.deletedAt IS NULL
+            MATCH (u:User {userID: $userID})
+            MERGE (u)-[f:FOLLOWS]->(target)
+            ON CREATE SET f.since = $since
+            RETURN f.since`,
+			map[string]any{
+				"userID":   userID,
+				"targetID": targetID,
+				"since":    since,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %s %s", ErrNotFound, target, targetID)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to follow",
+			slog.Any("error", err),
+			slog.Any("user_id", userID),
+			slog.Any("target", target),
+			slog.Any("target_id", targetID),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// Unfollow removes the FOLLOWS relationship of the user to the thread or the author, if there is one.
+func (s *Store) Unfollow(ctx context.Context, userID string, target model.FollowTarget, targetID string) error {
+	match, ok := followMatches[target]
+	if !ok {
+		return fmt.Errorf("unknown follow target: %s", target)
+	}
+
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(
+			ctx,
+			match+`
+            MATCH (:User {userID: $userID})-[f:FOLLOWS]->(target)
+            DELETE f`,
+			map[string]any{
+				"userID":   userID,
+				"targetID": targetID,
+			},
+		)
+		return nil, err
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to unfollow",
+			slog.Any("error", err),
+			slog.Any("user_id", userID),
+			slog.Any("target", target),
+			slog.Any("target_id", targetID),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// APIKey is a long-lived credential of a user for automated clients. Only the hash of the key is stored.
+type APIKey struct {
+	KeyID  string
+	UserID string
+	Name   string
+	Hash   string
+	Scopes []string
+
+	CreatedAt string
+	// ExpiresAt is empty for keys which don't expire
+	ExpiresAt  string
+	LastUsedAt string
+	RevokedAt  string
+}
+
+func APIKeyFrom(userID, name, hash string, scopes []string, expiresAt time.Time) *APIKey {
+	key := &APIKey{
+		UserID: userID,
+		Name:   name,
+		Hash:   hash,
+		Scopes: scopes,
+
+		CreatedAt: getValidTime().Format(time.RFC3339),
+	}
+	if !expiresAt.IsZero() {
+		key.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	}
+	return key
+}
+
+// Active reports whether the key is neither revoked nor expired at now.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != "" {
+		return false
+	}
+	return k.ExpiresAt == "" || k.ExpiresAt > now.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"strings"
+)
+
+// AuthorRole is the part a user had in writing a post. Authors and editors change the post, contributors are
+// credited only. Only authors invite co-authors.
+type AuthorRole string
+
+const (
+	AuthorRoleAuthor      AuthorRole = "author"
+	AuthorRoleEditor      AuthorRole = "editor"
+	AuthorRoleContributor AuthorRole = "contributor"
+)
+
+// ParseAuthorRole returns the author role with the given name.
+func ParseAuthorRole(name string) (AuthorRole, bool) {
+	switch role := AuthorRole(strings.ToLower(name)); role {
+	case AuthorRoleAuthor, AuthorRoleEditor, AuthorRoleContributor:
+		return role, true
+	default:
+		return "", false
+	}
+}
+
+// CanEdit reports whether authors with the role may change the post.
+func (r AuthorRole) CanEdit() bool {
+	return r == AuthorRoleAuthor || r == AuthorRoleEditor
+}
+
+// Author is a user credited on a post. Authors are ordered by Order, the user who created the post is the first.
+type Author struct {
+	UserID      string
+	Username    string
+	DisplayName string
+	Role        AuthorRole
+	Order       int
+}
+
+// Invitation asks a user to become a co-author of a post.
+type Invitation struct {
+	PostID    string
+	PostTitle string
+	UserID    string
+	Role      AuthorRole
+	InvitedBy string
+	InvitedAt string
+}
+
+// EditorIDs returns IDs of the authors who may change the post.
+func EditorIDs(authors []*Author) []string {
+	var ids []string
+	for _, author := range authors {
+		if author.Role.CanEdit() {
+			ids = append(ids, author.UserID)
+		}
+	}
+	return ids
+}
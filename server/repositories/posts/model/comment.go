@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"ndb/server/app/models"
+)
+
+type Comment struct {
+	CommentID string
+	PostID    string
+	// ParentID is empty for comments on the post itself, otherwise it is the ID of the comment replied to
+	ParentID string
+	UserID   string
+	Body     string
+
+	CreatedAt string
+	UpdatedAt string
+	DeletedAt string
+}
+
+func CommentFrom(postID string, comment *models.CreateCommentRequest) *Comment {
+	return &Comment{
+		PostID:   postID,
+		ParentID: comment.ParentID,
+		UserID:   comment.UserID,
+		Body:     comment.Body,
+
+		CreatedAt: getValidTime().Format(time.RFC3339),
+		UpdatedAt: getValidTime().Format(time.RFC3339),
+	}
+}
+
+// Cursor returns the cursor pointing at the comment, comments are always ordered from the oldest.
+func (c *Comment) Cursor() *Cursor {
+	return &Cursor{Sort: SortCreated, Key: c.CreatedAt, ID: c.CommentID}
+}
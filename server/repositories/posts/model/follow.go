@@ -0,0 +1,9 @@
+package model
+
+// FollowTarget is the kind of node a user follows to get its posts in the feed.
+type FollowTarget string
+
+const (
+	FollowThread FollowTarget = "thread"
+	FollowUser   FollowTarget = "user"
+)
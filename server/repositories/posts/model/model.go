@@ -1,7 +1,10 @@
 package model
 
 import (
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"ndb/server/app/models"
@@ -13,26 +16,41 @@ const (
 	StatusPublished PostStatus = "published"
 	StatusPrivate   PostStatus = "private"
 	StatusDeleted   PostStatus = "deleted"
+	StatusDraft     PostStatus = "draft"
+	StatusScheduled PostStatus = "scheduled"
 )
 
 type Post struct {
-	PostID   string
-	UserID   string
-	ThreadID string
-	Title    string
+	PostID     string
+	UserID     string
+	ThreadID   string
+	ThreadName string
+	Title      string
 
 	ContentFile string
 
-	ViewCount int
-	Status    PostStatus
-	CreatedAt string
-	UpdatedAt string
-	DeletedAt string
+	ViewCount    int
+	CommentCount int
+	Status       PostStatus
+	CreatedAt    string
+	UpdatedAt    string
+	DeletedAt    string
+	PublishAt    string
+
+	Description string
+	Tags        []string
+	CoverImage  string
+	PublishDate string
+
+	// BodyText is the plain text of the markdown content, used for full-text search
+	BodyText string
 }
 
+// PostFrom creates a post from the request. A post with publish date in the future is scheduled,
+// otherwise it is either kept as a draft or published right away.
 func PostFrom(post *models.CreatePostRequest) *Post {
-	return &Post{
-		UserID:   strconv.FormatInt(post.UserID, 10),
+	p := &Post{
+		UserID:   post.UserID,
 		ThreadID: post.Thread,
 		Title:    post.Title,
 
@@ -40,7 +58,58 @@ func PostFrom(post *models.CreatePostRequest) *Post {
 		Status:    StatusPublished,
 		CreatedAt: getValidTime().Format(time.RFC3339),
 		UpdatedAt: getValidTime().Format(time.RFC3339),
+
+		Description: post.Description,
+		Tags:        post.Tags,
+		CoverImage:  post.CoverImage,
+		PublishDate: formatDate(post.PublishDate),
+	}
+
+	switch {
+	case post.PublishAt.After(getValidTime()):
+		p.Status = StatusScheduled
+		p.PublishAt = post.PublishAt.UTC().Format(time.RFC3339)
+	case post.Draft:
+		p.Status = StatusDraft
+	}
+
+	return p
+}
+
+// ApplyUpdate overwrites the fields present in the request and bumps UpdatedAt. Publish date of a draft
+// or scheduled post schedules it.
+func (p *Post) ApplyUpdate(update *models.UpdatePostRequest) {
+	if update.Title != "" {
+		p.Title = update.Title
 	}
+	if update.Thread != "" {
+		p.ThreadID = update.Thread
+	}
+	if update.Description != "" {
+		p.Description = update.Description
+	}
+	if len(update.Tags) > 0 {
+		p.Tags = update.Tags
+	}
+	if update.CoverImage != "" {
+		p.CoverImage = update.CoverImage
+	}
+	if !update.PublishDate.IsZero() {
+		p.PublishDate = formatDate(update.PublishDate)
+	}
+	if !update.PublishAt.IsZero() && (p.Status == StatusDraft || p.Status == StatusScheduled) {
+		p.Status = StatusScheduled
+		p.PublishAt = update.PublishAt.UTC().Format(time.RFC3339)
+	}
+	p.UpdatedAt = getValidTime().Format(time.RFC3339)
+}
+
+// formatDate formats the date as RFC3339 in UTC, zero date is formatted as empty string.
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.UTC().Format(time.RFC3339)
 }
 
 func getValidTime() time.Time {
@@ -48,10 +117,48 @@ func getValidTime() time.Time {
 	return time.Now().In(loc)
 }
 
+type Revision struct {
+	Number      int
+	ContentFile string
+	ContentHash string
+	AuthorID    string
+	CreatedAt   string
+}
+
+// RevisionFrom creates a new revision of the post content, stored under a versioned file name.
+func RevisionFrom(post *Post, number int, contentHash, authorID string) *Revision {
+	return &Revision{
+		Number:      number,
+		ContentFile: RevisionFile(post.ContentFile, number),
+		ContentHash: contentHash,
+		AuthorID:    authorID,
+		CreatedAt:   getValidTime().Format(time.RFC3339),
+	}
+}
+
+// RevisionFile derives the file name of a revision from the post content file.
+// The first revision keeps the original "<uuid>.md" name, next ones are stored as "<uuid>.r<number>.md".
+func RevisionFile(contentFile string, number int) string {
+	base := strings.TrimSuffix(contentFile, ".md")
+	if i := strings.LastIndex(base, ".r"); i != -1 {
+		if _, err := strconv.Atoi(base[i+2:]); err == nil {
+			base = base[:i]
+		}
+	}
+
+	if number <= 1 {
+		return base + ".md"
+	}
+	return fmt.Sprintf("%s.r%d.md", base, number)
+}
+
 type Thread struct {
-	ThreadID string
-	Name     string
-	Tags     []string
+	ThreadID    string
+	Name        string
+	Description string
+	Tags        []string
+	// Archived threads keep their posts but accept no new ones
+	Archived bool
 
 	CreatedAt string
 	UpdatedAt string
@@ -60,10 +167,70 @@ type Thread struct {
 
 func ThreadFrom(thread *models.CreateThreadRequest) *Thread {
 	return &Thread{
-		Name: thread.Name,
-		Tags: thread.Tags,
+		Name:        thread.Name,
+		Description: thread.Description,
+		Tags:        thread.Tags,
 
 		CreatedAt: getValidTime().Format(time.RFC3339),
 		UpdatedAt: getValidTime().Format(time.RFC3339),
 	}
 }
+
+// ApplyUpdate overwrites the fields present in the request and bumps UpdatedAt. Tags are replaced first,
+// then the added and removed tags are applied to the result. Tags are compared by their slugs.
+func (t *Thread) ApplyUpdate(update *models.UpdateThreadRequest) {
+	if update.Name != "" {
+		t.Name = update.Name
+	}
+	if update.Description != nil {
+		t.Description = *update.Description
+	}
+	if update.Tags != nil {
+		t.Tags = nil
+		t.addTags(update.Tags)
+	}
+	t.addTags(update.AddTags)
+	t.Tags = slices.DeleteFunc(t.Tags, func(tag string) bool {
+		return slices.ContainsFunc(update.RemoveTags, func(removed string) bool {
+			return TagSlug(removed) == TagSlug(tag)
+		})
+	})
+	if update.Archived != nil {
+		t.Archived = *update.Archived
+	}
+	t.UpdatedAt = getValidTime().Format(time.RFC3339)
+}
+
+func (t *Thread) addTags(tags []string) {
+	for _, tag := range tags {
+		slug := TagSlug(tag)
+		if slug == "" || slices.ContainsFunc(t.Tags, func(existing string) bool { return TagSlug(existing) == slug }) {
+			continue
+		}
+		t.Tags = append(t.Tags, tag)
+	}
+}
+
+// ThreadDeletePolicy decides what happens to the posts of a deleted thread.
+type ThreadDeletePolicy string
+
+const (
+	// DeleteRefuse deletes only threads without posts outside of trash
+	DeleteRefuse ThreadDeletePolicy = "refuse"
+	// DeleteCascade moves the posts of the thread to trash together with the thread
+	DeleteCascade ThreadDeletePolicy = "cascade"
+	// DeleteMove moves the posts of the thread, including the ones in trash, to another thread
+	DeleteMove ThreadDeletePolicy = "move"
+)
+
+// ParseThreadDeletePolicy validates the policy, empty value defaults to refusing threads with posts.
+func ParseThreadDeletePolicy(policy string) (ThreadDeletePolicy, error) {
+	switch ThreadDeletePolicy(policy) {
+	case "":
+		return DeleteRefuse, nil
+	case DeleteRefuse, DeleteCascade, DeleteMove:
+		return ThreadDeletePolicy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown delete policy: %s", policy)
+	}
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"ndb/server/app/models"
+)
+
+func TestParseThreadDeletePolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    ThreadDeletePolicy
+		wantErr bool
+	}{
+		{policy: "", want: DeleteRefuse},
+		{policy: "refuse", want: DeleteRefuse},
+		{policy: "cascade", want: DeleteCascade},
+		{policy: "move", want: DeleteMove},
+		{policy: "Cascade", wantErr: true},
+		{policy: "purge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			got, err := ParseThreadDeletePolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThreadDeletePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseThreadDeletePolicy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadApplyUpdate(t *testing.T) {
+	description := "New description"
+	empty := ""
+	archived := true
+
+	tests := []struct {
+		name   string
+		update *models.UpdateThreadRequest
+		want   Thread
+	}{
+		{
+			name:   "empty update keeps everything",
+			update: &models.UpdateThreadRequest{},
+			want:   Thread{Name: "Go", Description: "About Go", Tags: []string{"go", "Backend"}},
+		},
+		{
+			name:   "name and description",
+			update: &models.UpdateThreadRequest{Name: "Golang", Description: &description},
+			want:   Thread{Name: "Golang", Description: description, Tags: []string{"go", "Backend"}},
+		},
+		{
+			name:   "empty description clears it",
+			update: &models.UpdateThreadRequest{Description: &empty},
+			want:   Thread{Name: "Go", Tags: []string{"go", "Backend"}},
+		},
+		{
+			name:   "tags replace all tags",
+			update: &models.UpdateThreadRequest{Tags: []string{"Databases", "databases", " "}},
+			want:   Thread{Name: "Go", Description: "About Go", Tags: []string{"Databases"}},
+		},
+		{
+			name:   "added tags skip existing slugs",
+			update: &models.UpdateThreadRequest{AddTags: []string{"Go", "Testing"}},
+			want:   Thread{Name: "Go", Description: "About Go", Tags: []string{"go", "Backend", "Testing"}},
+		},
+		{
+			name:   "removed tags match by slug",
+			update: &models.UpdateThreadRequest{RemoveTags: []string{"backend", "missing"}},
+			want:   Thread{Name: "Go", Description: "About Go", Tags: []string{"go"}},
+		},
+		{
+			name:   "archive",
+			update: &models.UpdateThreadRequest{Archived: &archived},
+			want:   Thread{Name: "Go", Description: "About Go", Tags: []string{"go", "Backend"}, Archived: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := &Thread{
+				ThreadID:    "thread-1",
+				Name:        "Go",
+				Description: "About Go",
+				Tags:        []string{"go", "Backend"},
+				CreatedAt:   "2024-05-01T10:00:00Z",
+				UpdatedAt:   "2024-05-01T10:00:00Z",
+			}
+			thread.ApplyUpdate(tt.update)
+
+			if thread.Name != tt.want.Name || thread.Description != tt.want.Description ||
+				thread.Archived != tt.want.Archived || !slices.Equal(thread.Tags, tt.want.Tags) {
+				t.Errorf("ApplyUpdate() = %+v, want %+v", *thread, tt.want)
+			}
+			if thread.ThreadID != "thread-1" || thread.CreatedAt != "2024-05-01T10:00:00Z" {
+				t.Errorf("ApplyUpdate() changed the ID or creation date: %+v", *thread)
+			}
+			if thread.UpdatedAt == "2024-05-01T10:00:00Z" {
+				t.Error("ApplyUpdate() didn't update UpdatedAt")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type PostSort string
+
+const (
+	SortCreated PostSort = "created"
+	SortUpdated PostSort = "updated"
+	SortViews   PostSort = "views"
+	// SortRelevance orders search results by their score, it is not accepted by post listings.
+	SortRelevance PostSort = "relevance"
+)
+
+// ParsePostSort validates the sort order, empty value defaults to the newest posts first.
+func ParsePostSort(sort string) (PostSort, error) {
+	switch PostSort(sort) {
+	case "":
+		return SortCreated, nil
+	case SortCreated, SortUpdated, SortViews:
+		return PostSort(sort), nil
+	default:
+		return "", fmt.Errorf("unknown sort order: %s", sort)
+	}
+}
+
+// Cursor points at the last item of a page. Items are ordered by the sort key and then by ID,
+// so the pair is unique and the next page starts right after it.
+type Cursor struct {
+	Sort PostSort `json:"s"`
+	Key  string   `json:"k"`
+	ID   string   `json:"id"`
+}
+
+// PostFilter narrows post listings, empty fields don't filter. FollowerID limits posts to the feed of the user,
+// posts from threads and by authors the user follows.
+type PostFilter struct {
+	ThreadID   string
+	AuthorID   string
+	FollowerID string
+}
+
+// PageQuery describes a single page of posts, After is nil for the first page.
+type PageQuery struct {
+	Limit int
+	Sort  PostSort
+	After *Cursor
+}
+
+// CursorFor returns the cursor pointing at the post for the given sort order.
+func CursorFor(post *Post, sort PostSort) *Cursor {
+	cursor := &Cursor{Sort: sort, ID: post.PostID}
+	switch sort {
+	case SortUpdated:
+		cursor.Key = post.UpdatedAt
+	case SortViews:
+		cursor.Key = strconv.Itoa(post.ViewCount)
+	default:
+		cursor.Key = post.CreatedAt
+	}
+	return cursor
+}
+
+// KeyValue returns the cursor key in the type of the sorted property.
+func (c *Cursor) KeyValue() (any, error) {
+	switch c.Sort {
+	case SortViews:
+		views, err := strconv.ParseInt(c.Key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		return views, nil
+	case SortRelevance:
+		score, err := strconv.ParseFloat(c.Key, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		return score, nil
+	default:
+		return c.Key, nil
+	}
+}
+
+// Encode returns the opaque representation of the cursor handed out to clients.
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses the cursor received from a client, it has to be created for the same sort order.
+func DecodeCursor(encoded string, sort PostSort) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	cursor := &Cursor{}
+	if err = json.Unmarshal(b, cursor); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if cursor.Sort != sort || cursor.ID == "" {
+		return nil, fmt.Errorf("%w: cursor does not match sort order %s", ErrInvalidCursor, sort)
+	}
+
+	return cursor, nil
+}
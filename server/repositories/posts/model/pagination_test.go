@@ -0,0 +1,128 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor *Cursor
+		sort   PostSort
+	}{
+		{
+			name:   "created",
+			cursor: &Cursor{Sort: SortCreated, Key: "2024-05-01T10:00:00Z", ID: "post-1"},
+			sort:   SortCreated,
+		},
+		{
+			name:   "views",
+			cursor: &Cursor{Sort: SortViews, Key: "42", ID: "post-2"},
+			sort:   SortViews,
+		},
+		{
+			name:   "empty key",
+			cursor: &Cursor{Sort: SortUpdated, ID: "post-3"},
+			sort:   SortUpdated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.cursor.Encode(), tt.sort)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if *got != *tt.cursor {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		sort    PostSort
+	}{
+		{
+			name:    "not base64",
+			encoded: "not a cursor!",
+			sort:    SortCreated,
+		},
+		{
+			name:    "not json",
+			encoded: base64.RawURLEncoding.EncodeToString([]byte("created")),
+			sort:    SortCreated,
+		},
+		{
+			name:    "other sort order",
+			encoded: (&Cursor{Sort: SortViews, Key: "1", ID: "post-1"}).Encode(),
+			sort:    SortCreated,
+		},
+		{
+			name:    "missing id",
+			encoded: (&Cursor{Sort: SortCreated, Key: "2024-05-01T10:00:00Z"}).Encode(),
+			sort:    SortCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.encoded, tt.sort); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestCursorKeyValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  *Cursor
+		want    any
+		wantErr bool
+	}{
+		{
+			name:   "created",
+			cursor: &Cursor{Sort: SortCreated, Key: "2024-05-01T10:00:00Z"},
+			want:   "2024-05-01T10:00:00Z",
+		},
+		{
+			name:   "views",
+			cursor: &Cursor{Sort: SortViews, Key: "42"},
+			want:   int64(42),
+		},
+		{
+			name:   "relevance",
+			cursor: &Cursor{Sort: SortRelevance, Key: "1.5"},
+			want:   1.5,
+		},
+		{
+			name:    "views not a number",
+			cursor:  &Cursor{Sort: SortViews, Key: "many"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cursor.KeyValue()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Errorf("KeyValue() error = %v, want %v", err, ErrInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("KeyValue() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("KeyValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+// RelatedReason names a connection between two posts which makes one of them a recommendation for the other.
+type RelatedReason string
+
+const (
+	ReasonSharedTags RelatedReason = "shared_tags"
+	ReasonSameThread RelatedReason = "same_thread"
+	ReasonSameAuthor RelatedReason = "same_author"
+	ReasonCoRead     RelatedReason = "co_read"
+)
+
+// RelatedPost is a post recommended after another one, with the connections it was scored by.
+type RelatedPost struct {
+	Post  *Post
+	Score float64
+	// SharedTags are tags of both posts, given to the posts themselves or to their threads
+	SharedTags []string
+	SameThread bool
+	// SharedAuthors are IDs of users who wrote both posts
+	SharedAuthors []string
+	// CoReaders is the number of users who read both posts
+	CoReaders int
+}
+
+// Reasons returns the connections which contributed to the score, the strongest kinds first.
+func (r *RelatedPost) Reasons() []RelatedReason {
+	var reasons []RelatedReason
+	if len(r.SharedAuthors) > 0 {
+		reasons = append(reasons, ReasonSameAuthor)
+	}
+	if len(r.SharedTags) > 0 {
+		reasons = append(reasons, ReasonSharedTags)
+	}
+	if r.SameThread {
+		reasons = append(reasons, ReasonSameThread)
+	}
+	if r.CoReaders > 0 {
+		reasons = append(reasons, ReasonCoRead)
+	}
+	return reasons
+}
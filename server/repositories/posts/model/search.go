@@ -0,0 +1,26 @@
+package model
+
+import "strconv"
+
+// SearchQuery describes a page of full-text search results. Empty ThreadID and Tag don't filter the results.
+// Tag is a slug, posts tagged with its aliases or descendant tags match it too.
+type SearchQuery struct {
+	Query    string
+	ThreadID string
+	Tag      string
+	Page     *PageQuery
+}
+
+type SearchHit struct {
+	Post  *Post
+	Score float64
+}
+
+// Cursor returns the cursor pointing at the hit in the results ordered by relevance.
+func (h *SearchHit) Cursor() *Cursor {
+	return &Cursor{
+		Sort: SortRelevance,
+		Key:  strconv.FormatFloat(h.Score, 'g', -1, 64),
+		ID:   h.Post.PostID,
+	}
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"ndb/server/app/models"
+)
+
+// TagMatch tells whether a post has a tag itself, through its thread or both.
+type TagMatch string
+
+const (
+	TagMatchPost   TagMatch = "post"
+	TagMatchThread TagMatch = "thread"
+	TagMatchBoth   TagMatch = "both"
+)
+
+// TagMatchOf returns the match of a post tagged directly, through its thread or both.
+func TagMatchOf(direct, viaThread bool) TagMatch {
+	switch {
+	case direct && viaThread:
+		return TagMatchBoth
+	case direct:
+		return TagMatchPost
+	default:
+		return TagMatchThread
+	}
+}
+
+// TaggedPost is a post found by one of its tags.
+type TaggedPost struct {
+	Post  *Post
+	Match TagMatch
+}
+
+// Tag is a canonical tag. Posts and threads are tagged with its Name, which is a slug, DisplayName is how
+// the tag is shown. Aliases are other slugs resolved to the tag, Parent is the slug of the broader tag.
+type Tag struct {
+	Name        string
+	DisplayName string
+	Parent      string
+	Children    []string
+	Aliases     []string
+}
+
+// ApplyUpdate overwrites the fields present in the request. Aliases are added first, then the removed
+// ones are dropped, empty parent detaches the tag from its parent.
+func (t *Tag) ApplyUpdate(update *models.UpdateTagRequest) {
+	if update.DisplayName != nil {
+		t.DisplayName = strings.TrimSpace(*update.DisplayName)
+	}
+	if update.Parent != nil {
+		t.Parent = TagSlug(*update.Parent)
+	}
+	for _, alias := range update.AddAliases {
+		if alias = TagSlug(alias); alias != "" && !slices.Contains(t.Aliases, alias) {
+			t.Aliases = append(t.Aliases, alias)
+		}
+	}
+	t.Aliases = slices.DeleteFunc(t.Aliases, func(alias string) bool {
+		return slices.ContainsFunc(update.RemoveAliases, func(removed string) bool {
+			return TagSlug(removed) == alias
+		})
+	})
+}
+
+// TagMerge is the result of merging one tag into another.
+type TagMerge struct {
+	Source  string
+	Target  string
+	Posts   int
+	Threads int
+}
+
+// TagSlug returns the canonical form of a tag name: lower case words joined with dashes, so "Go", "go" and
+// " GO " are the same tag. Letters, digits and the + # . characters are kept, "C++" and "C#" stay apart.
+func TagSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' || r == '.':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// NewTags returns tags with the given names. Names with the same slug make one tag, displayed as the first
+// of them, names with no characters left in the slug are skipped.
+func NewTags(names []string) []*Tag {
+	var tags []*Tag
+	for _, name := range names {
+		slug := TagSlug(name)
+		if slug == "" || slices.ContainsFunc(tags, func(tag *Tag) bool { return tag.Name == slug }) {
+			continue
+		}
+		tags = append(tags, &Tag{Name: slug, DisplayName: strings.TrimSpace(name)})
+	}
+	return tags
+}
+
+// TagSort orders tags listed with their usage.
+type TagSort string
+
+const (
+	// TagSortPopular orders tags by the number of their published posts, then by their views
+	TagSortPopular TagSort = "popular"
+	TagSortName    TagSort = "name"
+	// TagSortTrending orders tags by the activity on their posts since a date, tags without activity are left out
+	TagSortTrending TagSort = "trending"
+)
+
+// ParseTagSort validates the sort order, empty value defaults to the most popular tags first.
+func ParseTagSort(sort string) (TagSort, error) {
+	switch TagSort(sort) {
+	case "":
+		return TagSortPopular, nil
+	case TagSortPopular, TagSortName, TagSortTrending:
+		return TagSort(sort), nil
+	default:
+		return "", fmt.Errorf("unknown sort order: %s", sort)
+	}
+}
+
+// TagUsageQuery describes a list of tags with their usage. Activity is counted since Since.
+type TagUsageQuery struct {
+	Sort  TagSort
+	Since string
+	Limit int
+}
+
+// TagUsage tells how much a tag is used. Threads and posts tagged with descendants of the tag count for the tag,
+// like they are listed under it. Recent counts are the posts updated, comments written and reads of the posts
+// since the date of the query, Trend is their weighted sum.
+type TagUsage struct {
+	Tag            *Tag
+	Threads        int
+	Posts          int
+	Views          int
+	RecentPosts    int
+	RecentComments int
+	RecentReads    int
+	Trend          float64
+}
@@ -0,0 +1,76 @@
+package model
+
+import "testing"
+
+func TestTagMatchOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		direct    bool
+		viaThread bool
+		want      TagMatch
+	}{
+		{name: "post", direct: true, want: TagMatchPost},
+		{name: "thread", viaThread: true, want: TagMatchThread},
+		{name: "both", direct: true, viaThread: true, want: TagMatchBoth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagMatchOf(tt.direct, tt.viaThread); got != tt.want {
+				t.Errorf("TagMatchOf(%v, %v) = %q, want %q", tt.direct, tt.viaThread, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want string
+	}{
+		{name: "lower case", tag: "Go", want: "go"},
+		{name: "spaces", tag: "Machine Learning", want: "machine-learning"},
+		{name: "separators collapse", tag: "  data -- science__2024 ", want: "data-science-2024"},
+		{name: "kept symbols", tag: "C++ & C#", want: "c++-c#"},
+		{name: "dots", tag: "Node.js", want: "node.js"},
+		{name: "unicode letters", tag: "Zażółć Gęślą", want: "zażółć-gęślą"},
+		{name: "no letters", tag: " - / ", want: ""},
+		{name: "empty", tag: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagSlug(tt.tag); got != tt.want {
+				t.Errorf("TagSlug(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagSort(t *testing.T) {
+	tests := []struct {
+		sort    string
+		want    TagSort
+		wantErr bool
+	}{
+		{sort: "", want: TagSortPopular},
+		{sort: "popular", want: TagSortPopular},
+		{sort: "name", want: TagSortName},
+		{sort: "trending", want: TagSortTrending},
+		{sort: "Popular", wantErr: true},
+		{sort: "views", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			got, err := ParseTagSort(tt.sort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTagSort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTagSort() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
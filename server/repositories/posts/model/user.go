@@ -0,0 +1,95 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"ndb/server/app/models"
+)
+
+// Role decides what a user is allowed to do.
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleAuthor Role = "author"
+	RoleReader Role = "reader"
+)
+
+// ParseRole returns the role with the given name.
+func ParseRole(name string) (Role, bool) {
+	switch role := Role(strings.ToLower(name)); role {
+	case RoleAdmin, RoleEditor, RoleAuthor, RoleReader:
+		return role, true
+	default:
+		return "", false
+	}
+}
+
+type User struct {
+	UserID       string
+	Username     string
+	Email        string
+	PasswordHash string
+	Role         Role
+
+	DisplayName string
+	Bio         string
+	// AvatarKey is the name of the avatar image in S3, empty when the user has no avatar
+	AvatarKey string
+
+	// OIDCIssuer and OIDCSubject identify users signing in through an OpenID Connect provider,
+	// they are empty for users with local passwords only
+	OIDCIssuer  string
+	OIDCSubject string
+
+	CreatedAt string
+	UpdatedAt string
+}
+
+// UserStats aggregates the published posts of a user.
+type UserStats struct {
+	PostCount  int
+	TotalViews int
+	Threads    []*ThreadContribution
+}
+
+// ThreadContribution counts the published posts of a user in a thread.
+type ThreadContribution struct {
+	ThreadID  string
+	Name      string
+	PostCount int
+}
+
+// UserFrom creates a user with the role from the registration request, the password has to be hashed already.
+// Usernames and emails are case-insensitive, so they are stored lowercased.
+func UserFrom(user *models.RegisterUserRequest, passwordHash string, role Role) *User {
+	displayName := user.DisplayName
+	if displayName == "" {
+		displayName = user.Username
+	}
+
+	return &User{
+		Username:     strings.ToLower(user.Username),
+		Email:        strings.ToLower(user.Email),
+		PasswordHash: passwordHash,
+		Role:         role,
+		DisplayName:  displayName,
+		Bio:          user.Bio,
+
+		CreatedAt: getValidTime().Format(time.RFC3339),
+		UpdatedAt: getValidTime().Format(time.RFC3339),
+	}
+}
+
+// ApplyUpdate overwrites the profile fields present in the request and bumps UpdatedAt.
+func (u *User) ApplyUpdate(update *models.UpdateUserRequest) {
+	if update.DisplayName != "" {
+		u.DisplayName = update.DisplayName
+	}
+	if update.Bio != "" {
+		u.Bio = update.Bio
+	}
+	u.UpdatedAt = getValidTime().Format(time.RFC3339)
+}
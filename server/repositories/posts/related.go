@@ -0,0 +1,162 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// relatedWeights score the connections of related posts. Each shared tag adds tagWeight, co-readers add
+// coReadWeight scaled by the logarithm of their number, so a few very popular posts don't take over.
+var relatedWeights = map[string]any{
+	"tagWeight":    2.0,
+	"threadWeight": 1.5,
+	"authorWeight": 3.0,
+	"coReadWeight": 1.0,
+}
+
+// RelatedPosts returns the published posts most related to the published post, the best first. Candidates are
+// found by traversing from the post through its tags, the tags of its thread, its thread, its authors and
+// its readers, and then scored by relatedWeights. It fails with ErrNotFound when the post is not published.
+func (s *Store) RelatedPosts(ctx context.Context, postID string, limit int) ([]*model.RelatedPost, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	params := map[string]any{
+		"id":    postID,
+		"limit": limit,
+	}
+	for name, weight := range relatedWeights {
+		params[name] = weight
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (src:Post {postID: $id})
+            WHERE src.status = 'published'
+            RETURN src.postID`,
+			params,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
+
+		res, err = tx.Run(
+			ctx,
+			`MATCH (src:Post {postID: $id})-[:BELONGS_TO]->(srcThread:Thread)
+            WITH src, srcThread,
+                 [(src)-[:HAS_TAG]->(tag:Tag) | tag] +
+                 [(srcThread)-[:HAS_TAG]->(tag:Tag) WHERE NOT (src)-[:HAS_TAG]->(tag) | tag] AS srcTags
+            CALL {
+                WITH srcTags
+                UNWIND srcTags AS tag
+                MATCH (tag)<-[:HAS_TAG]-(p:Post)
+                RETURN p
+                UNION
+                WITH srcTags
+                UNWIND srcTags AS tag
+                MATCH (tag)<-[:HAS_TAG]-(:Thread)<-[:BELONGS_TO]-(p:Post)
+                RETURN p
+                UNION
+                WITH srcThread
+                MATCH (srcThread)<-[:BELONGS_TO]-(p:Post)
+                RETURN p
+                UNION
+                WITH src
+                MATCH (src)<-[:AUTHORED]-(:User)-[:AUTHORED]->(p:Post)
+                RETURN p
+                UNION
+                WITH src
+                MATCH (src)<-[:READ]-(:User)-[:READ]->(p:Post)
+                RETURN p
+            }
+            WITH src, srcThread, srcTags, p
+            WHERE p <> src AND p.status = 'published'
+            MATCH (p)-[:BELONGS_TO]->(t:Thread)
+            WHERE t.deletedAt IS NULL
+            WITH p, t,
+                 t = srcThread AS sameThread,
+                 [tag IN srcTags WHERE (p)-[:HAS_TAG]->(tag) OR (t)-[:HAS_TAG]->(tag) | tag.name] AS sharedTags,
+                 [(src)<-[:AUTHORED]-(a:User)-[:AUTHORED]->(p) | a.userID] AS sharedAuthors,
+                 COUNT { (src)<-[:READ]-(:User)-[:READ]->(p) } AS coReaders
+            WITH p, t, sameThread, sharedTags, sharedAuthors, coReaders,
+                 size(sharedTags) * $tagWeight
+                 + CASE WHEN sameThread THEN $threadWeight ELSE 0.0 END
+                 + CASE WHEN size(sharedAuthors) > 0 THEN $authorWeight ELSE 0.0 END
+                 + $coReadWeight * log(1 + coReaders) AS score
+            RETURN p, t.threadID AS thread_id, t.name AS thread_name,
+                   score, sharedTags, sameThread, sharedAuthors, coReaders
+            ORDER BY score DESC, coalesce(p.viewCount, 0) DESC, p.postID DESC
+            LIMIT $limit`,
+			params,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var related []*model.RelatedPost
+		for res.Next(ctx) {
+			record := res.Record()
+			node := record.Values[0].(neo4j.Node)
+			post := mapToPost(&node)
+			post.ThreadID = record.Values[1].(string)
+			post.ThreadName = record.Values[2].(string)
+
+			relatedPost := &model.RelatedPost{
+				Post:       post,
+				Score:      record.Values[3].(float64),
+				SameThread: record.Values[5].(bool),
+				CoReaders:  int(record.Values[7].(int64)),
+			}
+			for _, tag := range record.Values[4].([]any) {
+				relatedPost.SharedTags = append(relatedPost.SharedTags, tag.(string))
+			}
+			for _, authorID := range record.Values[6].([]any) {
+				relatedPost.SharedAuthors = append(relatedPost.SharedAuthors, authorID.(string))
+			}
+			related = append(related, relatedPost)
+		}
+
+		return related, res.Err()
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get related posts", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	return result.([]*model.RelatedPost), nil
+}
+
+// RecordRead remembers that the user read the post, reads of the same post again are not counted.
+func (s *Store) RecordRead(ctx context.Context, userID, postID, readAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return tx.Run(
+			ctx,
+			`MATCH (u:User {userID: $userID})
+            MATCH (p:Post {postID: $postID})
+            MERGE (u)-[r:READ]->(p)
+            ON CREATE SET r.readAt = $readAt`,
+			map[string]any{
+				"userID": userID,
+				"postID": postID,
+				"readAt": readAt,
+			},
+		)
+	})
+
+	return err
+}
@@ -0,0 +1,82 @@
+package posts
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+const (
+	searchIndex       = "blogSearch"
+	searchIndexSchema = `CREATE FULLTEXT INDEX ` + searchIndex + ` IF NOT EXISTS
+        FOR (n:Post|Thread|Tag) ON EACH [n.title, n.bodyText, n.name]`
+)
+
+// SearchPosts finds published posts matching the full-text query in their title or body, or in the name of their
+// thread or tags. Posts found through their thread or tags get half of the score, so direct matches rank first.
+func (s *Store) SearchPosts(ctx context.Context, search *model.SearchQuery) ([]*model.SearchHit, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	params := map[string]any{
+		"query":    search.Query,
+		"threadID": search.ThreadID,
+		"tag":      search.Tag,
+	}
+	if err := addPageParams(params, search.Page); err != nil {
+		return nil, err
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := `
+            CALL db.index.fulltext.queryNodes('` + searchIndex + `', $query) YIELD node, score
+            WITH node, score,
+                 CASE
+                     WHEN node:Post THEN [node]
+                     WHEN node:Thread THEN [(p:Post)-[:BELONGS_TO]->(node) | p]
+                     ELSE [(p:Post)-[:HAS_TAG]->(node) | p] +
+                          [(p:Post)-[:BELONGS_TO]->(:Thread)-[:HAS_TAG]->(node) WHERE NOT (p)-[:HAS_TAG]->(node) | p]
+                 END AS matched
+            UNWIND matched AS p
+            WITH p, sum(CASE WHEN node:Post THEN score ELSE score / 2 END) AS score
+            MATCH (p)-[:BELONGS_TO]->(t:Thread)
+            WHERE p.status = 'published'
+              AND t.deletedAt IS NULL
+              AND ($threadID = '' OR t.threadID = $threadID)
+              AND ($tag = '' OR EXISTS {
+                       MATCH (root:Tag)<-[:CHILD_OF*0..]-(:Tag)<-[:HAS_TAG]-(n)
+                       WHERE (root.name = $tag OR EXISTS { (:TagAlias {name: $tag})-[:ALIAS_OF]->(root) })
+                         AND (n = p OR n = t)
+                   })
+              AND ($afterID IS NULL OR score < $afterKey OR (score = $afterKey AND p.postID < $afterID))
+            RETURN p, t.threadID AS thread_id, t.name AS thread_name, score
+            ORDER BY score DESC, p.postID DESC
+            LIMIT $limit`
+
+		res, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var hits []*model.SearchHit
+		for res.Next(ctx) {
+			record := res.Record()
+			node := record.Values[0].(neo4j.Node)
+			post := mapToPost(&node)
+			post.ThreadID = record.Values[1].(string)
+			post.ThreadName = record.Values[2].(string)
+			hits = append(hits, &model.SearchHit{Post: post, Score: record.Values[3].(float64)})
+		}
+
+		return hits, res.Err()
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to search posts", slog.Any("error", err), slog.Any("query", search.Query))
+		return nil, err
+	}
+
+	return result.([]*model.SearchHit), nil
+}
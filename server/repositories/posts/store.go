@@ -2,6 +2,7 @@ package posts
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
@@ -10,6 +11,11 @@ import (
 	"ndb/server/repositories/posts/model"
 )
 
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+)
+
 type Store struct {
 	conn neo4j.DriverWithContext
 	log  *slog.Logger
@@ -36,6 +42,138 @@ func NewStore(
 	return &Store{conn: driver, log: logger}, nil
 }
 
+// schema lists indexes and constraints the store relies on
+var schema = []string{
+	searchIndexSchema,
+	`CREATE CONSTRAINT userID IF NOT EXISTS FOR (u:User) REQUIRE u.userID IS UNIQUE`,
+	`CREATE CONSTRAINT userUsername IF NOT EXISTS FOR (u:User) REQUIRE u.username IS UNIQUE`,
+	`CREATE CONSTRAINT userEmail IF NOT EXISTS FOR (u:User) REQUIRE u.email IS UNIQUE`,
+	`CREATE CONSTRAINT userIdentity IF NOT EXISTS FOR (u:User) REQUIRE (u.oidcIssuer, u.oidcSubject) IS UNIQUE`,
+	`CREATE CONSTRAINT apiKeyHash IF NOT EXISTS FOR (k:APIKey) REQUIRE k.hash IS UNIQUE`,
+	`CREATE CONSTRAINT tagName IF NOT EXISTS FOR (t:Tag) REQUIRE t.name IS UNIQUE`,
+	`CREATE CONSTRAINT tagAliasName IF NOT EXISTS FOR (a:TagAlias) REQUIRE a.name IS UNIQUE`,
+	`CREATE INDEX postContentFile IF NOT EXISTS FOR (p:Post) ON (p.contentFile)`,
+}
+
+// migrations bring data written by older versions up to date, each of them can be run any number of times.
+var migrations = []string{
+	// Tags of posts used to be kept only in the tags property
+	`MATCH (p:Post)
+    WHERE size(coalesce(p.tags, [])) > 0 AND NOT EXISTS { (p)-[:HAS_TAG]->(:Tag) }
+    UNWIND p.tags AS name
+    MERGE (tag:Tag {name: name})
+    MERGE (p)-[:HAS_TAG]->(tag)`,
+	// Tags used to have only a name, it is how they were displayed
+	`MATCH (tag:Tag)
+    WHERE tag.displayName IS NULL
+    SET tag.displayName = tag.name`,
+	// Posts created before co-authoring have their author only in the userID property. The IDs were chosen by
+	// clients then and rarely belong to a user, those authors get a placeholder user which can't sign in. Its
+	// username is not a valid one, so it can't be taken by a registered user.
+	`MATCH (p:Post)
+    WHERE p.userID IS NOT NULL AND NOT EXISTS { (:User)-[:AUTHORED]->(p) }
+    MERGE (u:User {userID: p.userID})
+    ON CREATE SET u.username = 'legacy:' + p.userID,
+                  u.passwordHash = '',
+                  u.role = 'author',
+                  u.displayName = '',
+                  u.bio = '',
+                  u.legacy = true,
+                  u.createdAt = p.createdAt,
+                  u.updatedAt = p.createdAt
+    CREATE (u)-[:AUTHORED {order: 0, role: 'author', since: p.createdAt}]->(p)`,
+	// Users registered before roles were introduced keep writing, when they have posts already
+	`MATCH (u:User)
+    WHERE u.role IS NULL AND EXISTS { (u)-[:AUTHORED]->(:Post) }
+    SET u.role = 'author'`,
+}
+
+// EnsureIndexes creates indexes and constraints the store relies on, if they don't exist yet, and migrates
+// data written by older versions.
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	// Schema commands cannot run in a transaction together with data queries
+	for _, statement := range schema {
+		res, err := session.Run(ctx, statement, nil)
+		if err == nil {
+			// The statement runs lazily, its errors are reported when the result is consumed
+			_, err = res.Consume(ctx)
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to create schema", slog.Any("error", err), slog.Any("statement", statement))
+			return err
+		}
+	}
+
+	for _, statement := range migrations {
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			res, err := tx.Run(ctx, statement, nil)
+			if err != nil {
+				return nil, err
+			}
+			return res.Consume(ctx)
+		})
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to migrate data", slog.Any("error", err), slog.Any("statement", statement))
+			return err
+		}
+	}
+
+	return s.normalizeTags(ctx, session)
+}
+
+// normalizeTags merges tags created before tags were normalized into the tags of their slugs. Slugs can't be
+// computed in Cypher, so the names are read and compared here.
+func (s *Store) normalizeTags(ctx context.Context, session neo4j.SessionWithContext) error {
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(ctx, `MATCH (tag:Tag) RETURN tag.name`, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for res.Next(ctx) {
+			names = append(names, res.Record().Values[0].(string))
+		}
+		return names, res.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range result.([]string) {
+		slug := model.TagSlug(name)
+		if slug == "" || slug == name {
+			continue
+		}
+
+		_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(
+				ctx,
+				`MERGE (tag:Tag {name: $slug})
+                ON CREATE SET tag.displayName = $name`,
+				map[string]any{
+					"name": name,
+					"slug": slug,
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+			return mergeTags(ctx, tx, name, slug, false)
+		})
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to normalize tag", slog.Any("error", err), slog.Any("tag", name))
+			return err
+		}
+		s.log.InfoContext(ctx, "Tag normalized", slog.Any("tag", name), slog.Any("slug", slug))
+	}
+
+	return nil
+}
+
 func (s *Store) CreateThread(ctx context.Context, thread *model.Thread) (string, error) {
 	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
@@ -45,16 +183,19 @@ func (s *Store) CreateThread(ctx context.Context, thread *model.Thread) (string,
 		res, err := tx.Run(
 			ctx,
 			`CREATE (t:Thread {
-				threadID: $id,				
+				threadID: $id,
 				name: $name,
+				description: $description,
+				archived: false,
 				createdAt: $createdAt,
                 updatedAt: $updatedAt
 				}) RETURN t`,
 			map[string]any{
-				"id":        thread.ThreadID,
-				"name":      thread.Name,
-				"createdAt": thread.CreatedAt,
-				"updatedAt": thread.UpdatedAt,
+				"id":          thread.ThreadID,
+				"name":        thread.Name,
+				"description": nullable(thread.Description),
+				"createdAt":   thread.CreatedAt,
+				"updatedAt":   thread.UpdatedAt,
 			},
 		)
 		if err != nil {
@@ -78,33 +219,15 @@ func (s *Store) CreateThread(ctx context.Context, thread *model.Thread) (string,
 			return nil, err
 		}
 
-		// For each tag, either create a new tag or connect to an existing one
-		for _, tag := range thread.Tags {
-			_, err = tx.Run(ctx,
-				`MERGE (tag:Tag {name: $tagName})
-                 WITH tag
-                 MATCH (t:Thread {name: $threadName})
-                 MERGE (t)-[:HAS_TAG]->(tag)`,
-				map[string]any{
-					"tagName":    tag,
-					"threadName": thread.Name,
-				})
-			if err != nil {
-				s.log.ErrorContext(
-					ctx,
-					"Failed to add tag",
-					slog.Any("error", err),
-					slog.Any("thread", thread.Name),
-					slog.Any("tag", tag),
-				)
-				return nil, err
-			}
-			s.log.InfoContext(
+		if err = setThreadTags(ctx, tx, thread.ThreadID, thread.Tags); err != nil {
+			s.log.ErrorContext(
 				ctx,
-				"New tag added successfully",
+				"Failed to add tags",
+				slog.Any("error", err),
 				slog.Any("thread", thread.Name),
-				slog.Any("tag", tag),
+				slog.Any("tags", thread.Tags),
 			)
+			return nil, err
 		}
 
 		return thread.ThreadID, nil
@@ -123,12 +246,15 @@ func (s *Store) ListThreads(ctx context.Context) ([]*model.Thread, error) {
 		res, err := tx.Run(
 			ctx,
 			`MATCH (t: Thread)
+					WHERE t.deletedAt IS NULL
 					OPTIONAL MATCH (t)-[:HAS_TAG]->(tag:Tag)
 					RETURN t.name AS name,
 					t.threadID as id,
 					t.createdAt as created_at,
 					t.updatedAt as updated_at,
-					collect(tag.name) AS tags;`,
+					collect(tag.name) AS tags,
+					t.description AS description,
+					coalesce(t.archived, false) AS archived;`,
 			nil,
 		)
 
@@ -152,16 +278,18 @@ func (s *Store) ListThreads(ctx context.Context) ([]*model.Thread, error) {
 				tags[i] = fmt.Sprint(v)
 			}
 
-			threads = append(
-				threads,
-				&model.Thread{
-					Name:      record.Values[0].(string),
-					ThreadID:  record.Values[1].(string),
-					CreatedAt: record.Values[2].(string),
-					UpdatedAt: record.Values[3].(string),
-					Tags:      tags,
-				},
-			)
+			thread := &model.Thread{
+				Name:      record.Values[0].(string),
+				ThreadID:  record.Values[1].(string),
+				CreatedAt: record.Values[2].(string),
+				UpdatedAt: record.Values[3].(string),
+				Tags:      tags,
+				Archived:  record.Values[6].(bool),
+			}
+			if description, ok := record.Values[5].(string); ok {
+				thread.Description = description
+			}
+			threads = append(threads, thread)
 
 		}
 
@@ -177,7 +305,7 @@ func (s *Store) ListTags(ctx context.Context) ([]string, error) {
 	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
-	query := "MATCH (t:Tag) RETURN t.name AS tag_name"
+	query := "MATCH (t:Tag) RETURN t.name AS tag_name ORDER BY tag_name"
 	result, err := session.Run(ctx, query, nil)
 	if err != nil {
 		s.log.ErrorContext(
@@ -199,14 +327,21 @@ func (s *Store) ListTags(ctx context.Context) ([]string, error) {
 	return tags, nil
 }
 
-func (s *Store) CreatePost(ctx context.Context, post *model.Post, threadID string) (string, error) {
+func (s *Store) CreatePost(
+	ctx context.Context,
+	post *model.Post,
+	revision *model.Revision,
+	threadID string,
+) (string, error) {
 	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
 	post.PostID = uuid.New().String()
 	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		// Neo4j query to create the Post and connect it to the Thread node
+		// Neo4j query to create the Post and connect it to the Thread and its author
 		query := `MATCH (t:Thread {threadID: $thread})
+            WHERE t.deletedAt IS NULL AND NOT coalesce(t.archived, false)
+            MATCH (u:User {userID: $userID})
             CREATE (p:Post {
 				postID: $id,
                 userID: $userID,
@@ -214,13 +349,27 @@ func (s *Store) CreatePost(ctx context.Context, post *model.Post, threadID strin
                 contentFile: $contentFile,
                 viewCount: $viewCount,
                 status: $status,
+                publishAt: $publishAt,
                 createdAt: $createdAt,
-                updatedAt: $updatedAt
+                updatedAt: $updatedAt,
+                description: $description,
+                tags: $tags,
+                coverImage: $coverImage,
+                publishDate: $publishDate,
+                bodyText: $bodyText
             })-[:BELONGS_TO]->(t)
+            CREATE (u)-[:AUTHORED {order: 0, role: 'author', since: $createdAt}]->(p)
+            CREATE (p)-[:HAS_REVISION]->(:Revision {
+                number: $revision,
+                contentFile: $contentFile,
+                contentHash: $contentHash,
+                authorID: $userID,
+                createdAt: $createdAt
+            })
             RETURN p`
 
 		// Run the query with all posts data
-		_, err := tx.Run(
+		res, err := tx.Run(
 			ctx,
 			query,
 			map[string]any{
@@ -230,9 +379,17 @@ func (s *Store) CreatePost(ctx context.Context, post *model.Post, threadID strin
 				"contentFile": post.ContentFile,
 				"viewCount":   post.ViewCount,
 				"status":      post.Status,
+				"publishAt":   nullable(post.PublishAt),
 				"createdAt":   post.CreatedAt,
 				"updatedAt":   post.UpdatedAt,
+				"description": nullable(post.Description),
+				"tags":        post.Tags,
+				"coverImage":  nullable(post.CoverImage),
+				"publishDate": nullable(post.PublishDate),
+				"bodyText":    post.BodyText,
 				"thread":      threadID,
+				"revision":    revision.Number,
+				"contentHash": revision.ContentHash,
 			},
 		)
 		if err != nil {
@@ -244,6 +401,14 @@ func (s *Store) CreatePost(ctx context.Context, post *model.Post, threadID strin
 			return nil, err
 		}
 
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: open thread %s or user %s", ErrNotFound, threadID, post.UserID)
+		}
+
+		if err = setPostTags(ctx, tx, post.PostID, post.Tags); err != nil {
+			return nil, err
+		}
+
 		s.log.InfoContext(
 			ctx,
 			"New posts created successfully",
@@ -259,28 +424,51 @@ func (s *Store) CreatePost(ctx context.Context, post *model.Post, threadID strin
 }
 
 func (s *Store) GetPost(ctx context.Context, postID string) (*model.Post, error) {
+	return s.getPost(ctx, "postID", postID, `p.status = 'published'`)
+}
+
+// GetEditablePost returns the post whatever its status is, posts in trash are not found.
+func (s *Store) GetEditablePost(ctx context.Context, postID string) (*model.Post, error) {
+	return s.getPost(ctx, "postID", postID, `p.status <> 'deleted'`)
+}
+
+// GetDeletedPost returns the post which is in trash.
+func (s *Store) GetDeletedPost(ctx context.Context, postID string) (*model.Post, error) {
+	return s.getPost(ctx, "postID", postID, `p.status = 'deleted'`)
+}
+
+// GetPostByContentFile returns the post whose current content is in the file, whatever its status is. Posts in
+// trash are not found, nor are posts which have the file only as one of their older revisions.
+func (s *Store) GetPostByContentFile(ctx context.Context, contentFile string) (*model.Post, error) {
+	return s.getPost(ctx, "contentFile", contentFile, `p.status <> 'deleted'`)
+}
+
+// getPost returns the post whose property has the value and which meets the condition.
+func (s *Store) getPost(ctx context.Context, property, value, condition string) (*model.Post, error) {
 	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
 		query := `
-            MATCH (p:Post {postID: $postID})
-            WHERE p.status = 'published'
+            MATCH (p:Post {` + property + `: $value})
+            WHERE ` + condition + `
             RETURN p`
 
 		res, err := tx.Run(ctx, query, map[string]interface{}{
-			"postID": postID,
+			"value": value,
 		})
 		if err != nil {
 			return nil, err
 		}
 
-		record, err := res.Single(ctx)
-		if err != nil {
-			return nil, err
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: post with %s %s", ErrNotFound, property, value)
 		}
 
-		node := record.Values[0].(neo4j.Node)
+		node := res.Record().Values[0].(neo4j.Node)
 		return mapToPost(&node), nil
 	})
 
@@ -290,18 +478,368 @@ func (s *Store) GetPost(ctx context.Context, postID string) (*model.Post, error)
 	return result.(*model.Post), nil
 }
 
-func (s *Store) GetPostsInThread(ctx context.Context, threadID string) ([]*model.Post, error) {
+// UpdatePost saves the metadata of the post which is not in trash and, when revision is not nil, records it as
+// the current revision with bodyText as the searchable text, in one transaction. Baseline is recorded before
+// the revision like in AddRevision. With threadID the post is moved to that thread, which must be open. Nothing
+// is changed when the thread or the post is not found.
+//
+// Status and publishAt are saved only with expectedStatus, the status the post was read with. When the post
+// changed its status in the meantime, e.g. it was published by the worker, it fails with ErrConflict.
+func (s *Store) UpdatePost(
+	ctx context.Context,
+	post *model.Post,
+	threadID string,
+	baseline, revision *model.Revision,
+	bodyText string,
+	expectedStatus model.PostStatus,
+) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// The thread is checked before anything is written
+		if threadID != "" {
+			res, err := tx.Run(
+				ctx,
+				`MATCH (t:Thread {threadID: $thread})
+                WHERE t.deletedAt IS NULL AND NOT coalesce(t.archived, false)
+                RETURN t.threadID`,
+				map[string]any{
+					"thread": threadID,
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+			if !res.Next(ctx) {
+				if err = res.Err(); err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf("%w: open thread %s", ErrNotFound, threadID)
+			}
+		}
+
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})
+            WHERE p.status <> 'deleted'
+            SET p.title = $title,
+                p.updatedAt = $updatedAt,
+                p.description = $description,
+                p.tags = $tags,
+                p.coverImage = $coverImage,
+                p.publishDate = $publishDate
+            RETURN p.status`,
+			map[string]any{
+				"id":          post.PostID,
+				"title":       post.Title,
+				"updatedAt":   post.UpdatedAt,
+				"description": nullable(post.Description),
+				"tags":        post.Tags,
+				"coverImage":  nullable(post.CoverImage),
+				"publishDate": nullable(post.PublishDate),
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to update post",
+				slog.Any("error", err),
+				slog.Any("post_id", post.PostID),
+			)
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, post.PostID)
+		}
+
+		// The post is locked by the update above, so the status can't change before it is saved
+		if expectedStatus != "" {
+			if status := model.PostStatus(res.Record().Values[0].(string)); status != expectedStatus {
+				return nil, fmt.Errorf("%w: post %s is %s, not %s", ErrConflict, post.PostID, status, expectedStatus)
+			}
+
+			_, err = tx.Run(
+				ctx,
+				`MATCH (p:Post {postID: $id})
+                SET p.status = $status,
+                    p.publishAt = $publishAt`,
+				map[string]any{
+					"id":        post.PostID,
+					"status":    post.Status,
+					"publishAt": nullable(post.PublishAt),
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if baseline != nil {
+			if err = addBaselineRevision(ctx, tx, post.PostID, baseline); err != nil {
+				return nil, err
+			}
+		}
+		if revision != nil {
+			if err = addRevision(ctx, tx, post.PostID, revision, bodyText); err != nil {
+				return nil, err
+			}
+		}
+
+		if err = setPostTags(ctx, tx, post.PostID, post.Tags); err != nil {
+			return nil, err
+		}
+
+		if threadID == "" {
+			return nil, nil
+		}
+
+		// Move the post to another thread
+		_, err = tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})-[r:BELONGS_TO]->(:Thread)
+            MATCH (t:Thread {threadID: $thread})
+            DELETE r
+            CREATE (p)-[:BELONGS_TO]->(t)`,
+			map[string]any{
+				"id":     post.PostID,
+				"thread": threadID,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to move post to thread",
+				slog.Any("error", err),
+				slog.Any("post_id", post.PostID),
+				slog.Any("thread_id", threadID),
+			)
+			return nil, err
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log.InfoContext(
+		ctx,
+		"Post updated successfully",
+		slog.Any("post_id", post.PostID),
+	)
+	return nil
+}
+
+// SoftDeletePost marks the post as deleted, the status it had is kept so it can be restored later.
+func (s *Store) SoftDeletePost(ctx context.Context, postID, deletedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})
+            WHERE p.status <> 'deleted'
+            SET p.previousStatus = p.status,
+                p.status = 'deleted',
+                p.deletedAt = $deletedAt
+            RETURN p.postID`,
+			map[string]any{
+				"id":        postID,
+				"deletedAt": deletedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to delete post",
+				slog.Any("error", err),
+				slog.Any("post_id", postID),
+			)
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log.InfoContext(ctx, "Post moved to trash", slog.Any("post_id", postID))
+	return nil
+}
+
+// RestorePost brings a soft deleted post back to the status it had before deletion. Posts of deleted threads
+// stay in trash, restoring them fails with ErrConflict.
+func (s *Store) RestorePost(ctx context.Context, postID, updatedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})-[:BELONGS_TO]->(t:Thread)
+            WHERE p.status = 'deleted'
+            RETURN t.threadID, t.deletedAt IS NULL`,
+			map[string]any{
+				"id": postID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: deleted post %s", ErrNotFound, postID)
+		}
+		if open := res.Record().Values[1].(bool); !open {
+			return nil, fmt.Errorf("%w: thread %s of post %s is deleted", ErrConflict, res.Record().Values[0], postID)
+		}
+
+		_, err = tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})
+            SET p.status = coalesce(p.previousStatus, 'published'),
+                p.updatedAt = $updatedAt
+            REMOVE p.previousStatus, p.deletedAt`,
+			map[string]any{
+				"id":        postID,
+				"updatedAt": updatedAt,
+			},
+		)
+		return nil, err
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to restore post",
+			slog.Any("error", err),
+			slog.Any("post_id", postID),
+		)
+		return err
+	}
+
+	s.log.InfoContext(ctx, "Post restored from trash", slog.Any("post_id", postID))
+	return nil
+}
+
+// UnpublishPost turns the published post back into a draft, which can be published again with PublishPost.
+func (s *Store) UnpublishPost(ctx context.Context, postID, updatedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})
+            WHERE p.status = 'published'
+            SET p.status = 'draft',
+                p.updatedAt = $updatedAt
+            REMOVE p.publishAt
+            RETURN p.postID`,
+			map[string]any{
+				"id":        postID,
+				"updatedAt": updatedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to unpublish post",
+				slog.Any("error", err),
+				slog.Any("post_id", postID),
+			)
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: published post %s", ErrNotFound, postID)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log.InfoContext(ctx, "Post unpublished", slog.Any("post_id", postID))
+	return nil
+}
+
+// PublishPost publishes the draft or scheduled post right away and returns the status it had before.
+func (s *Store) PublishPost(ctx context.Context, postID, updatedAt string) (model.PostStatus, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})
+            WHERE p.status IN ['draft', 'scheduled']
+            WITH p, p.status AS previous
+            SET p.status = 'published',
+                p.updatedAt = $updatedAt
+            REMOVE p.publishAt
+            RETURN previous`,
+			map[string]any{
+				"id":        postID,
+				"updatedAt": updatedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to publish post",
+				slog.Any("error", err),
+				slog.Any("post_id", postID),
+			)
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: unpublished post %s", ErrNotFound, postID)
+		}
+
+		return model.PostStatus(res.Record().Values[0].(string)), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.log.InfoContext(ctx, "Post published", slog.Any("post_id", postID))
+	return result.(model.PostStatus), nil
+}
+
+// GetDeletedPosts returns soft deleted posts, the ones deleted before deletedBefore only, when it is not empty.
+func (s *Store) GetDeletedPosts(ctx context.Context, deletedBefore string) ([]*model.Post, error) {
 	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
 		query := `
-            MATCH (p:Post)-[:BELONGS_TO]->(t:Thread {threadID: $threadID})
-            WHERE p.status = 'published'
-            RETURN p`
+            MATCH (p:Post)
+            WHERE p.status = 'deleted'
+              AND ($before = '' OR p.deletedAt < $before)
+            OPTIONAL MATCH (p)-[:BELONGS_TO]->(t:Thread)
+            RETURN p, t.threadID AS thread_id
+            ORDER BY p.deletedAt DESC`
 
 		res, err := tx.Run(ctx, query, map[string]interface{}{
-			"threadID": threadID,
+			"before": deletedBefore,
 		})
 		if err != nil {
 			return nil, err
@@ -309,8 +847,13 @@ func (s *Store) GetPostsInThread(ctx context.Context, threadID string) ([]*model
 
 		var posts []*model.Post
 		for res.Next(ctx) {
-			node := res.Record().Values[0].(neo4j.Node)
-			posts = append(posts, mapToPost(&node))
+			record := res.Record()
+			node := record.Values[0].(neo4j.Node)
+			post := mapToPost(&node)
+			if threadID, ok := record.Values[1].(string); ok {
+				post.ThreadID = threadID
+			}
+			posts = append(posts, post)
 		}
 
 		return posts, nil
@@ -322,48 +865,418 @@ func (s *Store) GetPostsInThread(ctx context.Context, threadID string) ([]*model
 	return result.([]*model.Post), nil
 }
 
-func (s *Store) GetPostsWithLimit(ctx context.Context, limit int) (map[string][]*model.Post, error) {
+// DeletePost removes the post node together with its revisions, comments and all relationships.
+func (s *Store) DeletePost(ctx context.Context, postID string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(
+			ctx,
+			`MATCH (p:Post {postID: $id})
+            OPTIONAL MATCH (p)-[:HAS_REVISION]->(r:Revision)
+            OPTIONAL MATCH (c:Comment)-[:ON]->(p)
+            DETACH DELETE p, r, c`,
+			map[string]any{
+				"id": postID,
+			},
+		)
+		return nil, err
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to purge post",
+			slog.Any("error", err),
+			slog.Any("post_id", postID),
+		)
+		return err
+	}
+
+	s.log.InfoContext(ctx, "Post purged", slog.Any("post_id", postID))
+	return nil
+}
+
+// GetDraftPosts returns posts the user is an author of which are not published yet.
+func (s *Store) GetDraftPosts(ctx context.Context, userID string) ([]*model.Post, error) {
 	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
 		query := `
-           MATCH (t:Thread)
-OPTIONAL MATCH (t)-[:HAS_TAG]->(tag:Tag)
-WITH t, collect(tag.name) AS tags
-OPTIONAL MATCH (p:Post)-[:BELONGS_TO]->(t)
-  WHERE p.status = 'published'
-RETURN t.name AS thread_name, t.threadID, tags, collect(p)[..$limit] AS posts`
+            MATCH (:User {userID: $userID})-[:AUTHORED]->(p:Post)
+            WHERE p.status IN ['draft', 'scheduled']
+            OPTIONAL MATCH (p)-[:BELONGS_TO]->(t:Thread)
+            RETURN p, t.threadID AS thread_id
+            ORDER BY p.updatedAt DESC`
 
 		res, err := tx.Run(ctx, query, map[string]interface{}{
-			"limit": limit,
+			"userID": userID,
 		})
 		if err != nil {
 			return nil, err
 		}
 
-		var posts = make(map[string][]*model.Post) // Initialize the map
+		var posts []*model.Post
 		for res.Next(ctx) {
 			record := res.Record()
+			node := record.Values[0].(neo4j.Node)
+			post := mapToPost(&node)
+			if threadID, ok := record.Values[1].(string); ok {
+				post.ThreadID = threadID
+			}
+			posts = append(posts, post)
+		}
+
+		return posts, nil
+	})
 
-			// Get the key for the posts map (from the first value in the record)
-			key := record.Values[0].(string)
+	if err != nil {
+		return nil, err
+	}
+	return result.([]*model.Post), nil
+}
 
-			// Iterate over the posts in the 6th column (index 5)
-			for _, post := range record.Values[3].([]interface{}) {
-				p := post.(neo4j.Node)
-				mapped := mapToPost(&p)
-				mapped.ThreadID = record.Values[1].(string)
+// PublishDuePosts publishes draft and scheduled posts whose publish date is not later than now and returns their IDs.
+func (s *Store) PublishDuePosts(ctx context.Context, now string) ([]string, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
 
-				// Check if the key already exists in the map
-				if _, exists := posts[key]; !exists {
-					// If not, initialize an empty array for this key
-					posts[key] = []*model.Post{}
-				}
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (p:Post)
+            WHERE p.status IN ['draft', 'scheduled']
+              AND p.publishAt IS NOT NULL
+              AND p.publishAt <= $now
+            SET p.status = 'published',
+                p.updatedAt = $now
+            RETURN p.postID`,
+			map[string]any{
+				"now": now,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var postIDs []string
+		for res.Next(ctx) {
+			postIDs = append(postIDs, res.Record().Values[0].(string))
+		}
+
+		return postIDs, res.Err()
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to publish scheduled posts", slog.Any("error", err))
+		return nil, err
+	}
+	return result.([]string), nil
+}
+
+// IncrementViewCounts adds the counted views to the posts, counts are keyed by post ID.
+func (s *Store) IncrementViewCounts(ctx context.Context, counts map[string]int64) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	views := make([]map[string]any, 0, len(counts))
+	for postID, count := range counts {
+		views = append(views, map[string]any{
+			"postID": postID,
+			"count":  count,
+		})
+	}
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(
+			ctx,
+			`UNWIND $views AS view
+            MATCH (p:Post {postID: view.postID})
+            SET p.viewCount = coalesce(p.viewCount, 0) + view.count`,
+			map[string]any{
+				"views": views,
+			},
+		)
+		return nil, err
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to increment view counts", slog.Any("error", err))
+		return err
+	}
+
+	s.log.InfoContext(ctx, "View counts flushed", slog.Int("posts", len(counts)))
+	return nil
+}
+
+// AddRevision records a new revision of the post content and makes it the current one, bodyText replaces the
+// searchable text of the post. Posts created before revisions were tracked get baseline, their content before
+// the change, recorded first when it is not nil. It fails with ErrConflict when a revision with the same number
+// already exists.
+func (s *Store) AddRevision(
+	ctx context.Context,
+	postID string,
+	baseline, revision *model.Revision,
+	bodyText string,
+) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		if baseline != nil {
+			if err := addBaselineRevision(ctx, tx, postID, baseline); err != nil {
+				return nil, err
+			}
+		}
+		return nil, addRevision(ctx, tx, postID, revision, bodyText)
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to add revision",
+			slog.Any("error", err),
+			slog.Any("post_id", postID),
+			slog.Any("revision", revision.Number),
+		)
+		return err
+	}
+
+	s.log.InfoContext(
+		ctx,
+		"Revision added successfully",
+		slog.Any("post_id", postID),
+		slog.Any("revision", revision.Number),
+	)
+	return nil
+}
+
+func addRevision(
+	ctx context.Context,
+	tx neo4j.ManagedTransaction,
+	postID string,
+	revision *model.Revision,
+	bodyText string,
+) error {
+	res, err := tx.Run(
+		ctx,
+		`MATCH (p:Post {postID: $id})
+        OPTIONAL MATCH (p)-[:HAS_REVISION]->(existing:Revision {number: $number})
+        WITH p, existing
+        WHERE existing IS NULL
+        CREATE (p)-[:HAS_REVISION]->(:Revision {
+            number: $number,
+            contentFile: $contentFile,
+            contentHash: $contentHash,
+            authorID: $authorID,
+            createdAt: $createdAt
+        })
+        SET p.contentFile = $contentFile,
+            p.updatedAt = $createdAt,
+            p.bodyText = $bodyText
+        RETURN p.postID`,
+		map[string]any{
+			"id":          postID,
+			"bodyText":    bodyText,
+			"number":      revision.Number,
+			"contentFile": revision.ContentFile,
+			"contentHash": revision.ContentHash,
+			"authorID":    revision.AuthorID,
+			"createdAt":   revision.CreatedAt,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if !res.Next(ctx) {
+		if err = res.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: revision %d of post %s already exists", ErrConflict, revision.Number, postID)
+	}
+
+	return nil
+}
+
+// addBaselineRevision records the revision of the post which has no revisions yet, the post is not changed.
+func addBaselineRevision(
+	ctx context.Context,
+	tx neo4j.ManagedTransaction,
+	postID string,
+	baseline *model.Revision,
+) error {
+	res, err := tx.Run(
+		ctx,
+		`MATCH (p:Post {postID: $id})
+        WHERE NOT EXISTS { (p)-[:HAS_REVISION]->(:Revision) }
+        CREATE (p)-[:HAS_REVISION]->(:Revision {
+            number: $number,
+            contentFile: $contentFile,
+            contentHash: $contentHash,
+            authorID: $authorID,
+            createdAt: $createdAt
+        })
+        RETURN p.postID`,
+		map[string]any{
+			"id":          postID,
+			"number":      baseline.Number,
+			"contentFile": baseline.ContentFile,
+			"contentHash": baseline.ContentHash,
+			"authorID":    baseline.AuthorID,
+			"createdAt":   baseline.CreatedAt,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if !res.Next(ctx) {
+		if err = res.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: post %s already has revisions", ErrConflict, postID)
+	}
+
+	return nil
+}
+
+// GetRevisions returns revisions of the post, the newest first.
+func (s *Store) GetRevisions(ctx context.Context, postID string) ([]*model.Revision, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := `
+            MATCH (:Post {postID: $postID})-[:HAS_REVISION]->(r:Revision)
+            RETURN r
+            ORDER BY r.number DESC`
+
+		res, err := tx.Run(ctx, query, map[string]interface{}{
+			"postID": postID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var revisions []*model.Revision
+		for res.Next(ctx) {
+			node := res.Record().Values[0].(neo4j.Node)
+			revisions = append(revisions, mapToRevision(&node))
+		}
+
+		return revisions, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result.([]*model.Revision), nil
+}
+
+func (s *Store) GetRevision(ctx context.Context, postID string, number int) (*model.Revision, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := `
+            MATCH (:Post {postID: $postID})-[:HAS_REVISION]->(r:Revision {number: $number})
+            RETURN r`
+
+		res, err := tx.Run(ctx, query, map[string]interface{}{
+			"postID": postID,
+			"number": number,
+		})
+		if err != nil {
+			return nil, err
+		}
 
-				// Append the mapped post to the array
-				posts[key] = append(posts[key], mapped)
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
 			}
+			return nil, fmt.Errorf("%w: revision %d of post %s", ErrNotFound, number, postID)
+		}
+
+		node := res.Record().Values[0].(neo4j.Node)
+		return mapToRevision(&node), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.Revision), nil
+}
+
+// sortKeys maps sort orders to the sorted property, ORDER BY cannot be parametrized.
+var sortKeys = map[model.PostSort]string{
+	model.SortCreated: "p.createdAt",
+	model.SortUpdated: "p.updatedAt",
+	model.SortViews:   "coalesce(p.viewCount, 0)",
+}
+
+// ListPosts returns a page of published posts matching the filter. Posts of an author are found by traversing
+// the AUTHORED relationships of the user, the feed of a follower by traversing its FOLLOWS relationships to
+// threads and authors. One post more than the limit is fetched, so the caller knows whether there is a next page.
+func (s *Store) ListPosts(ctx context.Context, filter *model.PostFilter, page *model.PageQuery) ([]*model.Post, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	key, ok := sortKeys[page.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order: %s", page.Sort)
+	}
+
+	params := map[string]any{
+		"threadID":   filter.ThreadID,
+		"authorID":   filter.AuthorID,
+		"followerID": filter.FollowerID,
+	}
+	if err := addPageParams(params, page); err != nil {
+		return nil, err
+	}
+
+	match := `MATCH (p:Post)-[:BELONGS_TO]->(t:Thread)`
+	switch {
+	case filter.AuthorID != "":
+		match = `MATCH (:User {userID: $authorID})-[:AUTHORED]->(p:Post)-[:BELONGS_TO]->(t:Thread)`
+	case filter.FollowerID != "":
+		// UNION drops posts reached both through a followed thread and a followed author
+		match = `MATCH (follower:User {userID: $followerID})
+            CALL {
+                WITH follower
+                MATCH (follower)-[:FOLLOWS]->(:Thread)<-[:BELONGS_TO]-(p:Post)
+                RETURN p
+                UNION
+                WITH follower
+                MATCH (follower)-[:FOLLOWS]->(:User)-[:AUTHORED]->(p:Post)
+                RETURN p
+            }
+            MATCH (p)-[:BELONGS_TO]->(t:Thread)`
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := fmt.Sprintf(`
+            %[2]s
+            WHERE p.status = 'published'
+              AND t.deletedAt IS NULL
+              AND ($threadID = '' OR t.threadID = $threadID)
+              AND ($afterID IS NULL OR %[1]s < $afterKey OR (%[1]s = $afterKey AND p.postID < $afterID))
+            RETURN p, t.threadID AS thread_id, t.name AS thread_name
+            ORDER BY %[1]s DESC, p.postID DESC
+            LIMIT $limit`, key, match)
+
+		res, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var posts []*model.Post
+		for res.Next(ctx) {
+			record := res.Record()
+			node := record.Values[0].(neo4j.Node)
+			post := mapToPost(&node)
+			post.ThreadID = record.Values[1].(string)
+			post.ThreadName = record.Values[2].(string)
+			posts = append(posts, post)
 		}
 
 		return posts, nil
@@ -372,7 +1285,24 @@ RETURN t.name AS thread_name, t.threadID, tags, collect(p)[..$limit] AS posts`
 	if err != nil {
 		return nil, err
 	}
-	return result.(map[string][]*model.Post), nil
+	return result.([]*model.Post), nil
+}
+
+// addPageParams adds the limit and the cursor of the page to the query parameters. One post more than
+// the limit is fetched, so the caller knows whether there is a next page.
+func addPageParams(params map[string]any, page *model.PageQuery) error {
+	params["limit"] = page.Limit + 1
+	params["afterKey"] = nil
+	params["afterID"] = nil
+	if page.After != nil {
+		afterKey, err := page.After.KeyValue()
+		if err != nil {
+			return err
+		}
+		params["afterKey"] = afterKey
+		params["afterID"] = page.After.ID
+	}
+	return nil
 }
 
 func mapToPost(node *neo4j.Node) *model.Post {
@@ -386,5 +1316,49 @@ func mapToPost(node *neo4j.Node) *model.Post {
 		CreatedAt:   node.Props["createdAt"].(string),
 		UpdatedAt:   node.Props["updatedAt"].(string),
 	}
+	if deletedAt, ok := node.Props["deletedAt"].(string); ok {
+		post.DeletedAt = deletedAt
+	}
+	if publishAt, ok := node.Props["publishAt"].(string); ok {
+		post.PublishAt = publishAt
+	}
+	if description, ok := node.Props["description"].(string); ok {
+		post.Description = description
+	}
+	if tags, ok := node.Props["tags"].([]any); ok {
+		for _, tag := range tags {
+			post.Tags = append(post.Tags, fmt.Sprint(tag))
+		}
+	}
+	if coverImage, ok := node.Props["coverImage"].(string); ok {
+		post.CoverImage = coverImage
+	}
+	if publishDate, ok := node.Props["publishDate"].(string); ok {
+		post.PublishDate = publishDate
+	}
+	if commentCount, ok := node.Props["commentCount"].(int64); ok {
+		post.CommentCount = int(commentCount)
+	}
+	if bodyText, ok := node.Props["bodyText"].(string); ok {
+		post.BodyText = bodyText
+	}
 	return &post
 }
+
+func mapToRevision(node *neo4j.Node) *model.Revision {
+	return &model.Revision{
+		Number:      int(node.Props["number"].(int64)),
+		ContentFile: node.Props["contentFile"].(string),
+		ContentHash: node.Props["contentHash"].(string),
+		AuthorID:    node.Props["authorID"].(string),
+		CreatedAt:   node.Props["createdAt"].(string),
+	}
+}
+
+// nullable maps empty strings to nil, so the property is not set on the node at all.
+func nullable(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
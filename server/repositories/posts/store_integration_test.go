@@ -0,0 +1,141 @@
+//go:build integration
+
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"ndb/server/app/models"
+	"ndb/server/config"
+	"ndb/server/repositories/posts/model"
+)
+
+// newTestStore connects to the Neo4j from docker-compose.
+// Run the tests with the compose services up: go test -tags integration ./server/repositories/posts/
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	ctx := context.Background()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(ctx, slog.New(slog.NewTextHandler(os.Stderr, nil)), &cfg.Neo4j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.EnsureIndexes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// createTestDraft creates a draft in a new thread, written by a new user.
+func createTestDraft(t *testing.T, store *Store) *model.Post {
+	t.Helper()
+	ctx := context.Background()
+	name := fmt.Sprintf("store-test-%d", time.Now().UnixNano())
+
+	userID, err := store.CreateUser(ctx, &model.User{
+		Username: name,
+		Email:    name + "@example.com",
+		Role:     model.RoleAuthor,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	threadID, err := store.CreateThread(ctx, model.ThreadFrom(&models.CreateThreadRequest{Name: name}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := model.PostFrom(&models.CreatePostRequest{UserID: userID, Thread: threadID, Title: name, Draft: true})
+	post.ContentFile = name + ".md"
+	postID, err := store.CreatePost(ctx, post, model.RevisionFrom(post, 1, "hash", userID), threadID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post, err = store.GetEditablePost(ctx, postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func TestUpdatePostSchedule(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	publishAt := time.Now().Add(24 * time.Hour)
+
+	t.Run("draft is scheduled", func(t *testing.T) {
+		post := createTestDraft(t, store)
+
+		expected := post.Status
+		post.ApplyUpdate(&models.UpdatePostRequest{PublishAt: publishAt})
+		if err := store.UpdatePost(ctx, post, "", nil, nil, "", expected); err != nil {
+			t.Fatalf("UpdatePost() error = %v", err)
+		}
+
+		got, err := store.GetEditablePost(ctx, post.PostID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != model.StatusScheduled || got.PublishAt == "" {
+			t.Errorf("post is %s with publishAt %q, want it scheduled", got.Status, got.PublishAt)
+		}
+	})
+
+	t.Run("post published after it was read", func(t *testing.T) {
+		post := createTestDraft(t, store)
+
+		// The post is read by the update, then published before the update is saved
+		if _, err := store.PublishPost(ctx, post.PostID, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := post.Status
+		post.ApplyUpdate(&models.UpdatePostRequest{Title: "Scheduled", PublishAt: publishAt})
+		err := store.UpdatePost(ctx, post, "", nil, nil, "", expected)
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("UpdatePost() error = %v, want ErrConflict", err)
+		}
+
+		got, err := store.GetPost(ctx, post.PostID)
+		if err != nil {
+			t.Fatalf("post is not published anymore: %v", err)
+		}
+		if got.Title == "Scheduled" || got.PublishAt != "" {
+			t.Errorf("failed update changed the post: %+v", got)
+		}
+	})
+
+	t.Run("update without schedule keeps the status", func(t *testing.T) {
+		post := createTestDraft(t, store)
+
+		if _, err := store.PublishPost(ctx, post.PostID, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			t.Fatal(err)
+		}
+
+		post.ApplyUpdate(&models.UpdatePostRequest{Title: "Renamed"})
+		if err := store.UpdatePost(ctx, post, "", nil, nil, "", ""); err != nil {
+			t.Fatalf("UpdatePost() error = %v", err)
+		}
+
+		got, err := store.GetPost(ctx, post.PostID)
+		if err != nil {
+			t.Fatalf("post is not published anymore: %v", err)
+		}
+		if got.Title != "Renamed" {
+			t.Errorf("title = %q, want %q", got.Title, "Renamed")
+		}
+	})
+}
@@ -0,0 +1,525 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// ListTaggedPosts returns a page of published posts tagged directly or through their thread, with the tag or
+// any of its descendants. The tag may be given by one of its aliases. Posts with the tag in both places are
+// returned once, the match tells which relationships lead to the tag.
+func (s *Store) ListTaggedPosts(ctx context.Context, tag string, page *model.PageQuery) ([]*model.TaggedPost, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	key, ok := sortKeys[page.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order: %s", page.Sort)
+	}
+
+	params := map[string]any{
+		"tag": tag,
+	}
+	if err := addPageParams(params, page); err != nil {
+		return nil, err
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := fmt.Sprintf(`
+            MATCH (root:Tag)
+            WHERE root.name = $tag OR EXISTS { (:TagAlias {name: $tag})-[:ALIAS_OF]->(root) }
+            MATCH (tag:Tag)-[:CHILD_OF*0..]->(root)
+            CALL {
+                WITH tag
+                MATCH (p:Post)-[:HAS_TAG]->(tag)
+                RETURN p
+                UNION
+                WITH tag
+                MATCH (p:Post)-[:BELONGS_TO]->(:Thread)-[:HAS_TAG]->(tag)
+                RETURN p
+            }
+            WITH DISTINCT root, p
+            MATCH (p)-[:BELONGS_TO]->(t:Thread)
+            WHERE p.status = 'published'
+              AND t.deletedAt IS NULL
+              AND ($afterID IS NULL OR %[1]s < $afterKey OR (%[1]s = $afterKey AND p.postID < $afterID))
+            RETURN p, t.threadID AS thread_id, t.name AS thread_name,
+                   EXISTS { (p)-[:HAS_TAG]->(:Tag)-[:CHILD_OF*0..]->(root) } AS direct,
+                   EXISTS { (t)-[:HAS_TAG]->(:Tag)-[:CHILD_OF*0..]->(root) } AS via_thread
+            ORDER BY %[1]s DESC, p.postID DESC
+            LIMIT $limit`, key)
+
+		res, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var posts []*model.TaggedPost
+		for res.Next(ctx) {
+			record := res.Record()
+			node := record.Values[0].(neo4j.Node)
+			post := mapToPost(&node)
+			post.ThreadID = record.Values[1].(string)
+			post.ThreadName = record.Values[2].(string)
+			posts = append(posts, &model.TaggedPost{
+				Post:  post,
+				Match: model.TagMatchOf(record.Values[3].(bool), record.Values[4].(bool)),
+			})
+		}
+
+		return posts, res.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*model.TaggedPost), nil
+}
+
+// trendWeights score the recent activity on posts of a tag, new and updated posts count the most.
+var trendWeights = map[string]any{
+	"postWeight":    3.0,
+	"commentWeight": 2.0,
+	"readWeight":    1.0,
+}
+
+// tagSortOrders are the ORDER BY clauses of tag usage sort orders
+var tagSortOrders = map[model.TagSort]string{
+	model.TagSortPopular:  "posts DESC, views DESC, root.name",
+	model.TagSortName:     "root.name",
+	model.TagSortTrending: "trend DESC, posts DESC, root.name",
+}
+
+// ListTagUsage returns tags with the number of their threads which are not deleted, their published posts, the views
+// of these posts and the activity on them since the date of the query.
+func (s *Store) ListTagUsage(ctx context.Context, query *model.TagUsageQuery) ([]*model.TagUsage, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	order, ok := tagSortOrders[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order: %s", query.Sort)
+	}
+
+	params := map[string]any{
+		"since":    query.Since,
+		"limit":    query.Limit,
+		"trending": query.Sort == model.TagSortTrending,
+	}
+	for name, weight := range trendWeights {
+		params[name] = weight
+	}
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(ctx, fmt.Sprintf(`
+            MATCH (root:Tag)
+            CALL {
+                WITH root
+                MATCH (tag:Tag)-[:CHILD_OF*0..]->(root)
+                OPTIONAL MATCH (tag)<-[:HAS_TAG]-(t:Thread)
+                WHERE t.deletedAt IS NULL
+                RETURN count(DISTINCT t) AS threads
+            }
+            CALL {
+                WITH root
+                MATCH (tag:Tag)-[:CHILD_OF*0..]->(root)
+                CALL {
+                    WITH tag
+                    MATCH (p:Post)-[:HAS_TAG]->(tag)
+                    RETURN p
+                    UNION
+                    WITH tag
+                    MATCH (p:Post)-[:BELONGS_TO]->(:Thread)-[:HAS_TAG]->(tag)
+                    RETURN p
+                }
+                WITH DISTINCT p
+                WHERE p.status = 'published'
+                  AND EXISTS { (p)-[:BELONGS_TO]->(thread:Thread) WHERE thread.deletedAt IS NULL }
+                RETURN count(p) AS posts,
+                       sum(coalesce(p.viewCount, 0)) AS views,
+                       count(CASE WHEN p.updatedAt >= $since THEN 1 END) AS recentPosts,
+                       sum(COUNT { (c:Comment)-[:ON]->(p) WHERE c.deletedAt IS NULL AND c.createdAt >= $since })
+                           AS recentComments,
+                       sum(COUNT { (:User)-[r:READ]->(p) WHERE r.readAt >= $since }) AS recentReads
+            }
+            WITH root, threads, posts, views, recentPosts, recentComments, recentReads,
+                 recentPosts * $postWeight + recentComments * $commentWeight + recentReads * $readWeight AS trend
+            WHERE NOT $trending OR trend > 0
+            RETURN root.name, coalesce(root.displayName, root.name),
+                   threads, posts, views, recentPosts, recentComments, recentReads, trend
+            ORDER BY %s
+            LIMIT $limit`, order), params)
+		if err != nil {
+			return nil, err
+		}
+
+		var usage []*model.TagUsage
+		for res.Next(ctx) {
+			record := res.Record()
+			usage = append(usage, &model.TagUsage{
+				Tag: &model.Tag{
+					Name:        record.Values[0].(string),
+					DisplayName: record.Values[1].(string),
+				},
+				Threads:        int(record.Values[2].(int64)),
+				Posts:          int(record.Values[3].(int64)),
+				Views:          int(record.Values[4].(int64)),
+				RecentPosts:    int(record.Values[5].(int64)),
+				RecentComments: int(record.Values[6].(int64)),
+				RecentReads:    int(record.Values[7].(int64)),
+				Trend:          record.Values[8].(float64),
+			})
+		}
+
+		return usage, res.Err()
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to list tag usage", slog.Any("error", err), slog.Any("sort", query.Sort))
+		return nil, err
+	}
+
+	return result.([]*model.TagUsage), nil
+}
+
+// setPostTags connects the post to exactly the given tags, creating the ones which don't exist yet. Names are
+// normalized to slugs and aliases are resolved to their tags, the tags property of the post gets the resolved
+// names in the order in which they were given.
+func setPostTags(ctx context.Context, tx neo4j.ManagedTransaction, postID string, names []string) error {
+	slugs, tags := tagParams(names)
+
+	_, err := tx.Run(
+		ctx,
+		`MATCH (p:Post {postID: $id})
+        OPTIONAL MATCH (p)-[r:HAS_TAG]->(old:Tag)
+        WHERE NOT old.name IN $slugs
+        DELETE r
+        WITH DISTINCT p
+        UNWIND $tags AS given
+        OPTIONAL MATCH (:TagAlias {name: given.name})-[:ALIAS_OF]->(canonical:Tag)
+        WITH p, given, coalesce(canonical.name, given.name) AS name
+        MERGE (tag:Tag {name: name})
+        ON CREATE SET tag.displayName = given.displayName
+        MERGE (p)-[:HAS_TAG]->(tag)
+        WITH p, collect(DISTINCT name) AS names
+        SET p.tags = names`,
+		map[string]any{
+			"id":    postID,
+			"slugs": slugs,
+			"tags":  tags,
+		},
+	)
+	return err
+}
+
+// tagParams normalizes tag names to the slugs compared with stored tags and to the slugs and display names
+// of tags which may need to be created.
+func tagParams(names []string) ([]string, []map[string]any) {
+	slugs := []string{}
+	tags := []map[string]any{}
+	for _, tag := range model.NewTags(names) {
+		slugs = append(slugs, tag.Name)
+		tags = append(tags, map[string]any{
+			"name":        tag.Name,
+			"displayName": tag.DisplayName,
+		})
+	}
+	return slugs, tags
+}
+
+// GetTag returns the tag with the slug, or the tag the slug is an alias of, with its parent, children and aliases.
+func (s *Store) GetTag(ctx context.Context, name string) (*model.Tag, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (tag:Tag)
+            WHERE tag.name = $name OR EXISTS { (:TagAlias {name: $name})-[:ALIAS_OF]->(tag) }
+            OPTIONAL MATCH (tag)-[:CHILD_OF]->(parent:Tag)
+            RETURN tag.name, coalesce(tag.displayName, tag.name), parent.name,
+                   [(child:Tag)-[:CHILD_OF]->(tag) | child.name] AS children,
+                   [(alias:TagAlias)-[:ALIAS_OF]->(tag) | alias.name] AS aliases`,
+			map[string]any{
+				"name": name,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: tag %s", ErrNotFound, name)
+		}
+
+		record := res.Record()
+		tag := &model.Tag{
+			Name:        record.Values[0].(string),
+			DisplayName: record.Values[1].(string),
+		}
+		if parent, ok := record.Values[2].(string); ok {
+			tag.Parent = parent
+		}
+		for _, child := range record.Values[3].([]any) {
+			tag.Children = append(tag.Children, child.(string))
+		}
+		for _, alias := range record.Values[4].([]any) {
+			tag.Aliases = append(tag.Aliases, alias.(string))
+		}
+
+		return tag, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*model.Tag), nil
+}
+
+// UpdateTag saves the display name of the tag and replaces its parent and aliases, in one transaction. The parent
+// may be given by one of its aliases. It fails with ErrNotFound when the tag or the parent doesn't exist and with
+// ErrConflict when the parent is the tag or one of its descendants, or when an alias is a tag or an alias of
+// another tag.
+func (s *Store) UpdateTag(ctx context.Context, tag *model.Tag) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	params := map[string]any{
+		"name":        tag.Name,
+		"displayName": tag.DisplayName,
+		"parent":      tag.Parent,
+		"aliases":     tag.Aliases,
+	}
+	if tag.Aliases == nil {
+		params["aliases"] = []string{}
+	}
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(ctx, `MATCH (tag:Tag {name: $name}) RETURN tag.name`, params)
+		if err != nil {
+			return nil, err
+		}
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: tag %s", ErrNotFound, tag.Name)
+		}
+
+		if tag.Parent != "" {
+			res, err = tx.Run(
+				ctx,
+				`MATCH (parent:Tag)
+                WHERE parent.name = $parent OR EXISTS { (:TagAlias {name: $parent})-[:ALIAS_OF]->(parent) }
+                RETURN parent.name, EXISTS { (parent)-[:CHILD_OF*0..]->(:Tag {name: $name}) } AS cycle`,
+				params,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if !res.Next(ctx) {
+				if err = res.Err(); err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf("%w: parent tag %s", ErrNotFound, tag.Parent)
+			}
+			if res.Record().Values[1].(bool) {
+				return nil, fmt.Errorf("%w: tag %s can't be placed under %s", ErrConflict, tag.Name, tag.Parent)
+			}
+			params["parent"] = res.Record().Values[0].(string)
+		}
+
+		res, err = tx.Run(
+			ctx,
+			`UNWIND $aliases AS alias
+            WITH alias
+            WHERE EXISTS { (:Tag {name: alias}) }
+               OR EXISTS { (:TagAlias {name: alias})-[:ALIAS_OF]->(other:Tag) WHERE other.name <> $name }
+            RETURN alias`,
+			params,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if res.Next(ctx) {
+			return nil, fmt.Errorf("%w: %s is already a tag or an alias", ErrConflict, res.Record().Values[0])
+		}
+		if err = res.Err(); err != nil {
+			return nil, err
+		}
+
+		statements := []string{
+			`MATCH (tag:Tag {name: $name})
+            SET tag.displayName = $displayName
+            WITH tag
+            OPTIONAL MATCH (tag)-[r:CHILD_OF]->(:Tag)
+            DELETE r`,
+			`MATCH (alias:TagAlias)-[:ALIAS_OF]->(:Tag {name: $name})
+            WHERE NOT alias.name IN $aliases
+            DETACH DELETE alias`,
+			`MATCH (tag:Tag {name: $name})
+            UNWIND $aliases AS name
+            MERGE (alias:TagAlias {name: name})
+            MERGE (alias)-[:ALIAS_OF]->(tag)`,
+			`MATCH (tag:Tag {name: $name})
+            MATCH (parent:Tag {name: $parent})
+            CREATE (tag)-[:CHILD_OF]->(parent)`,
+		}
+		for _, statement := range statements {
+			if _, err = tx.Run(ctx, statement, params); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to update tag", slog.Any("error", err), slog.Any("tag", tag.Name))
+		return err
+	}
+
+	s.log.InfoContext(ctx, "Tag updated", slog.Any("tag", tag.Name))
+	return nil
+}
+
+// MergeTags merges the source tag into the target tag, which may be given by one of its aliases, in one
+// transaction. Posts and threads tagged with the source are tagged with the target instead, children and aliases
+// of the source move to the target and the source becomes an alias of the target. It fails with ErrNotFound
+// when either tag doesn't exist and with ErrConflict when they are the same tag.
+func (s *Store) MergeTags(ctx context.Context, source, target string) (*model.TagMerge, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return mergeTags(ctx, tx, source, target, true)
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to merge tags",
+			slog.Any("error", err),
+			slog.Any("source", source),
+			slog.Any("target", target),
+		)
+		return nil, err
+	}
+
+	merge := result.(*model.TagMerge)
+	s.log.InfoContext(
+		ctx,
+		"Tags merged",
+		slog.Any("source", merge.Source),
+		slog.Any("target", merge.Target),
+		slog.Any("posts", merge.Posts),
+		slog.Any("threads", merge.Threads),
+	)
+	return merge, nil
+}
+
+// mergeTags rewires everything connected to the source tag to the target tag and deletes the source. With alias
+// the name of the source is kept as an alias of the target.
+func mergeTags(
+	ctx context.Context,
+	tx neo4j.ManagedTransaction,
+	source, target string,
+	alias bool,
+) (*model.TagMerge, error) {
+	params := map[string]any{
+		"source": source,
+		"target": target,
+	}
+
+	res, err := tx.Run(
+		ctx,
+		`MATCH (source:Tag {name: $source})
+        MATCH (target:Tag)
+        WHERE target.name = $target OR EXISTS { (:TagAlias {name: $target})-[:ALIAS_OF]->(target) }
+        RETURN target.name`,
+		params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Next(ctx) {
+		if err = res.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: tag %s or %s", ErrNotFound, source, target)
+	}
+	target = res.Record().Values[0].(string)
+	if target == source {
+		return nil, fmt.Errorf("%w: %s and %s are the same tag", ErrConflict, source, params["target"])
+	}
+	params["target"] = target
+
+	res, err = tx.Run(
+		ctx,
+		`MATCH (source:Tag {name: $source})
+        MATCH (target:Tag {name: $target})
+        MATCH (n)-[r:HAS_TAG]->(source)
+        MERGE (n)-[:HAS_TAG]->(target)
+        DELETE r
+        RETURN count(CASE WHEN n:Post THEN 1 END) AS posts, count(CASE WHEN n:Thread THEN 1 END) AS threads`,
+		params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	record, err := res.Single(ctx)
+	if err != nil {
+		return nil, err
+	}
+	merge := &model.TagMerge{
+		Source:  source,
+		Target:  target,
+		Posts:   int(record.Values[0].(int64)),
+		Threads: int(record.Values[1].(int64)),
+	}
+
+	statements := []string{
+		// The tags property keeps the order of tags, the renamed tag takes the place of the source
+		`MATCH (p:Post)-[:HAS_TAG]->(:Tag {name: $target})
+        WHERE $source IN p.tags
+        WITH p, [name IN p.tags | CASE WHEN name = $source THEN $target ELSE name END] AS renamed
+        SET p.tags = reduce(tags = [], name IN renamed | CASE WHEN name IN tags THEN tags ELSE tags + name END)`,
+		// Children which are ancestors of the target would make a cycle, they stay where they are
+		`MATCH (target:Tag {name: $target})
+        MATCH (child:Tag)-[r:CHILD_OF]->(:Tag {name: $source})
+        WHERE child <> target AND NOT EXISTS { (target)-[:CHILD_OF*]->(child) }
+        MERGE (child)-[:CHILD_OF]->(target)
+        DELETE r`,
+		`MATCH (target:Tag {name: $target})
+        MATCH (alias:TagAlias)-[r:ALIAS_OF]->(:Tag {name: $source})
+        MERGE (alias)-[:ALIAS_OF]->(target)
+        DELETE r`,
+		`MATCH (source:Tag {name: $source})
+        DETACH DELETE source`,
+	}
+	if alias {
+		statements = append(
+			statements,
+			`MATCH (target:Tag {name: $target})
+            MERGE (alias:TagAlias {name: $source})
+            WITH target, alias
+            OPTIONAL MATCH (alias)-[r:ALIAS_OF]->(:Tag)
+            DELETE r
+            WITH DISTINCT target, alias
+            MERGE (alias)-[:ALIAS_OF]->(target)`,
+		)
+	}
+	for _, statement := range statements {
+		if _, err = tx.Run(ctx, statement, params); err != nil {
+			return nil, err
+		}
+	}
+
+	return merge, nil
+}
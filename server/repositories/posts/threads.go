@@ -0,0 +1,286 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// GetThread returns the thread with its tags, deleted threads are not found.
+func (s *Store) GetThread(ctx context.Context, threadID string) (*model.Thread, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (t:Thread {threadID: $id})
+            WHERE t.deletedAt IS NULL
+            OPTIONAL MATCH (t)-[:HAS_TAG]->(tag:Tag)
+            RETURN t, collect(tag.name) AS tags`,
+			map[string]any{
+				"id": threadID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: thread %s", ErrNotFound, threadID)
+		}
+
+		record := res.Record()
+		node := record.Values[0].(neo4j.Node)
+		thread := mapToThread(&node)
+		for _, tag := range record.Values[1].([]any) {
+			thread.Tags = append(thread.Tags, fmt.Sprint(tag))
+		}
+
+		return thread, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*model.Thread), nil
+}
+
+// UpdateThread saves the name, description and archived flag of the thread and replaces its tags.
+// Tags which are no longer used by any thread are kept, so they can be attached again.
+func (s *Store) UpdateThread(ctx context.Context, thread *model.Thread) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	// Tags are compared with their slugs, which are never null, otherwise no tag would be removed
+	slugs, _ := tagParams(thread.Tags)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (t:Thread {threadID: $id})
+            WHERE t.deletedAt IS NULL
+            SET t.name = $name,
+                t.description = $description,
+                t.archived = $archived,
+                t.updatedAt = $updatedAt
+            WITH t
+            OPTIONAL MATCH (t)-[r:HAS_TAG]->(tag:Tag)
+            WHERE NOT tag.name IN $slugs
+            DELETE r
+            RETURN DISTINCT t.threadID`,
+			map[string]any{
+				"id":          thread.ThreadID,
+				"name":        thread.Name,
+				"description": nullable(thread.Description),
+				"archived":    thread.Archived,
+				"updatedAt":   thread.UpdatedAt,
+				"slugs":       slugs,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: thread %s", ErrNotFound, thread.ThreadID)
+		}
+
+		return nil, setThreadTags(ctx, tx, thread.ThreadID, thread.Tags)
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to update thread", slog.Any("error", err), slog.Any("thread_id", thread.ThreadID))
+		return err
+	}
+
+	s.log.InfoContext(ctx, "Thread updated", slog.Any("thread_id", thread.ThreadID))
+	return nil
+}
+
+// DeleteThread marks the thread as deleted and handles its posts according to the policy, in one transaction.
+// Posts moved to trash by DeleteCascade get the deletion date of the thread. With DeleteMove all posts of the
+// thread, including the ones in trash, are moved to the open thread with targetID, archived threads don't take
+// new posts. It returns the posts moved to trash or to the other thread, as they were before, and fails with
+// ErrConflict when DeleteRefuse finds posts outside of trash.
+func (s *Store) DeleteThread(
+	ctx context.Context,
+	threadID string,
+	policy model.ThreadDeletePolicy,
+	targetID, deletedAt string,
+) ([]*model.Post, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	params := map[string]any{
+		"id":        threadID,
+		"targetID":  targetID,
+		"deletedAt": deletedAt,
+	}
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (t:Thread {threadID: $id})
+            WHERE t.deletedAt IS NULL
+            OPTIONAL MATCH (p:Post)-[:BELONGS_TO]->(t)
+            WHERE p.status <> 'deleted'
+            RETURN count(p) AS posts`,
+			params,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: thread %s", ErrNotFound, threadID)
+		}
+		live := res.Record().Values[0].(int64)
+
+		var selected, query string
+		switch policy {
+		case model.DeleteRefuse:
+			if live > 0 {
+				return nil, fmt.Errorf("%w: thread %s has %d posts", ErrConflict, threadID, live)
+			}
+		case model.DeleteCascade:
+			selected = `MATCH (p:Post)-[:BELONGS_TO]->(:Thread {threadID: $id})
+                WHERE p.status <> 'deleted'
+                RETURN p`
+			query = `MATCH (p:Post)-[:BELONGS_TO]->(:Thread {threadID: $id})
+                WHERE p.status <> 'deleted'
+                SET p.previousStatus = p.status,
+                    p.status = 'deleted',
+                    p.deletedAt = $deletedAt`
+		case model.DeleteMove:
+			res, err = tx.Run(
+				ctx,
+				`MATCH (target:Thread {threadID: $targetID})
+                WHERE target.deletedAt IS NULL AND NOT coalesce(target.archived, false) AND target.threadID <> $id
+                RETURN target.threadID`,
+				params,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if !res.Next(ctx) {
+				if err = res.Err(); err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf("%w: open target thread %s", ErrNotFound, targetID)
+			}
+
+			selected = `MATCH (p:Post)-[:BELONGS_TO]->(:Thread {threadID: $id})
+                RETURN p`
+			query = `MATCH (p:Post)-[r:BELONGS_TO]->(:Thread {threadID: $id})
+                MATCH (target:Thread {threadID: $targetID})
+                DELETE r
+                CREATE (p)-[:BELONGS_TO]->(target)`
+		default:
+			return nil, fmt.Errorf("unknown delete policy: %s", policy)
+		}
+
+		// Posts are read before they are changed, so they can be returned as they were
+		posts := []*model.Post{}
+		if query != "" {
+			res, err = tx.Run(ctx, selected, params)
+			if err != nil {
+				return nil, err
+			}
+			for res.Next(ctx) {
+				node := res.Record().Values[0].(neo4j.Node)
+				post := mapToPost(&node)
+				post.ThreadID = threadID
+				posts = append(posts, post)
+			}
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+
+			if _, err = tx.Run(ctx, query, params); err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = tx.Run(
+			ctx,
+			`MATCH (t:Thread {threadID: $id})
+            SET t.deletedAt = $deletedAt,
+                t.updatedAt = $deletedAt`,
+			params,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return posts, nil
+	})
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to delete thread",
+			slog.Any("error", err),
+			slog.Any("thread_id", threadID),
+			slog.Any("policy", policy),
+		)
+		return nil, err
+	}
+
+	s.log.InfoContext(ctx, "Thread deleted", slog.Any("thread_id", threadID), slog.Any("policy", policy))
+	return result.([]*model.Post), nil
+}
+
+// setThreadTags connects the thread to the tags, creating the ones which don't exist yet. Names are normalized
+// to slugs and aliases are resolved to their tags.
+func setThreadTags(ctx context.Context, tx neo4j.ManagedTransaction, threadID string, names []string) error {
+	_, tags := tagParams(names)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Run(
+		ctx,
+		`MATCH (t:Thread {threadID: $id})
+        UNWIND $tags AS given
+        OPTIONAL MATCH (:TagAlias {name: given.name})-[:ALIAS_OF]->(canonical:Tag)
+        MERGE (tag:Tag {name: coalesce(canonical.name, given.name)})
+        ON CREATE SET tag.displayName = given.displayName
+        MERGE (t)-[:HAS_TAG]->(tag)`,
+		map[string]any{
+			"id":   threadID,
+			"tags": tags,
+		},
+	)
+	return err
+}
+
+func mapToThread(node *neo4j.Node) *model.Thread {
+	thread := &model.Thread{
+		ThreadID:  node.Props["threadID"].(string),
+		Name:      node.Props["name"].(string),
+		CreatedAt: node.Props["createdAt"].(string),
+		UpdatedAt: node.Props["updatedAt"].(string),
+	}
+	if description, ok := node.Props["description"].(string); ok {
+		thread.Description = description
+	}
+	if archived, ok := node.Props["archived"].(bool); ok {
+		thread.Archived = archived
+	}
+	if deletedAt, ok := node.Props["deletedAt"].(string); ok {
+		thread.DeletedAt = deletedAt
+	}
+	return thread
+}
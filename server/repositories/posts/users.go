@@ -0,0 +1,314 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// CreateUser stores a new user. It fails with ErrConflict when the username or email is already taken.
+// Users provisioned from an OpenID Connect provider may have no email.
+func (s *Store) CreateUser(ctx context.Context, user *model.User) (string, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	user.UserID = uuid.New().String()
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`OPTIONAL MATCH (existing:User)
+            WHERE existing.username = $username OR existing.email = $email
+            WITH count(existing) AS taken
+            WHERE taken = 0
+            CREATE (u:User {
+                userID: $id,
+                username: $username,
+                email: $email,
+                passwordHash: $passwordHash,
+                role: $role,
+                displayName: $displayName,
+                bio: $bio,
+                oidcIssuer: $oidcIssuer,
+                oidcSubject: $oidcSubject,
+                createdAt: $createdAt,
+                updatedAt: $updatedAt
+            })
+            RETURN u.userID`,
+			map[string]any{
+				"id":           user.UserID,
+				"username":     user.Username,
+				"email":        nullable(user.Email),
+				"passwordHash": user.PasswordHash,
+				"role":         string(user.Role),
+				"displayName":  user.DisplayName,
+				"bio":          user.Bio,
+				"oidcIssuer":   nullable(user.OIDCIssuer),
+				"oidcSubject":  nullable(user.OIDCSubject),
+				"createdAt":    user.CreatedAt,
+				"updatedAt":    user.UpdatedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to create user", slog.Any("error", err), slog.Any("username", user.Username))
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: username or email is already taken", ErrConflict)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.log.InfoContext(ctx, "User created successfully", slog.Any("user_id", user.UserID))
+	return user.UserID, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, userID string) (*model.User, error) {
+	return s.getUser(ctx, "userID", userID)
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	return s.getUser(ctx, "username", username)
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return s.getUser(ctx, "email", email)
+}
+
+// GetUserByIdentity returns the user signing in with the subject of the OpenID Connect issuer.
+func (s *Store) GetUserByIdentity(ctx context.Context, issuer, subject string) (*model.User, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User {oidcIssuer: $issuer, oidcSubject: $subject}) RETURN u`,
+			map[string]any{
+				"issuer":  issuer,
+				"subject": subject,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: user with subject %s", ErrNotFound, subject)
+		}
+
+		node := res.Record().Values[0].(neo4j.Node)
+		return mapToUser(&node), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*model.User), nil
+}
+
+// LinkIdentity lets an existing user sign in with the subject of the OpenID Connect issuer. It fails with
+// ErrConflict when the user is already linked to another identity.
+func (s *Store) LinkIdentity(ctx context.Context, userID, issuer, subject, updatedAt string) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User {userID: $id})
+            WHERE u.oidcSubject IS NULL OR (u.oidcIssuer = $issuer AND u.oidcSubject = $subject)
+            SET u.oidcIssuer = $issuer,
+                u.oidcSubject = $subject,
+                u.updatedAt = $updatedAt
+            RETURN u.userID`,
+			map[string]any{
+				"id":        userID,
+				"issuer":    issuer,
+				"subject":   subject,
+				"updatedAt": updatedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to link identity", slog.Any("error", err), slog.Any("user_id", userID))
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: user %s is linked to another identity", ErrConflict, userID)
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+func (s *Store) getUser(ctx context.Context, property, value string) (*model.User, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			fmt.Sprintf(`MATCH (u:User {%s: $value}) RETURN u`, property),
+			map[string]any{
+				"value": value,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: user %s", ErrNotFound, value)
+		}
+
+		node := res.Record().Values[0].(neo4j.Node)
+		return mapToUser(&node), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*model.User), nil
+}
+
+// UpdateUser replaces the profile fields and the role of the user.
+func (s *Store) UpdateUser(ctx context.Context, user *model.User) error {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User {userID: $id})
+            SET u.displayName = $displayName,
+                u.bio = $bio,
+                u.avatarKey = $avatarKey,
+                u.role = $role,
+                u.updatedAt = $updatedAt
+            RETURN u.userID`,
+			map[string]any{
+				"id":          user.UserID,
+				"displayName": user.DisplayName,
+				"bio":         user.Bio,
+				"avatarKey":   nullable(user.AvatarKey),
+				"role":        string(user.Role),
+				"updatedAt":   user.UpdatedAt,
+			},
+		)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to update user", slog.Any("error", err), slog.Any("user_id", user.UserID))
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			return nil, fmt.Errorf("%w: user %s", ErrNotFound, user.UserID)
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
+
+// GetUserStats counts published posts of the user, their views and the threads they belong to,
+// following the AUTHORED relationships of the user.
+func (s *Store) GetUserStats(ctx context.Context, userID string) (*model.UserStats, error) {
+	session := s.conn.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(
+			ctx,
+			`MATCH (u:User {userID: $id})
+            OPTIONAL MATCH (u)-[:AUTHORED]->(p:Post {status: 'published'})-[:BELONGS_TO]->(t:Thread)
+            WHERE t.deletedAt IS NULL
+            WITH t, count(p) AS posts, sum(coalesce(p.viewCount, 0)) AS views
+            ORDER BY posts DESC, t.name
+            RETURN sum(posts) AS post_count,
+                   sum(views) AS total_views,
+                   collect(CASE WHEN t IS NULL THEN null
+                                ELSE {threadID: t.threadID, name: t.name, posts: posts} END) AS threads`,
+			map[string]any{
+				"id": userID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.Next(ctx) {
+			if err = res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: user %s", ErrNotFound, userID)
+		}
+
+		record := res.Record()
+		stats := &model.UserStats{
+			PostCount:  int(record.Values[0].(int64)),
+			TotalViews: int(record.Values[1].(int64)),
+			Threads:    []*model.ThreadContribution{},
+		}
+		for _, value := range record.Values[2].([]any) {
+			thread := value.(map[string]any)
+			stats.Threads = append(stats.Threads, &model.ThreadContribution{
+				ThreadID:  thread["threadID"].(string),
+				Name:      thread["name"].(string),
+				PostCount: int(thread["posts"].(int64)),
+			})
+		}
+
+		return stats, nil
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get user stats", slog.Any("error", err), slog.Any("user_id", userID))
+		return nil, err
+	}
+
+	return result.(*model.UserStats), nil
+}
+
+func mapToUser(node *neo4j.Node) *model.User {
+	user := model.User{
+		UserID:      node.Props["userID"].(string),
+		Username:    node.Props["username"].(string),
+		Role:        model.RoleReader,
+		DisplayName: node.Props["displayName"].(string),
+		Bio:         node.Props["bio"].(string),
+		CreatedAt:   node.Props["createdAt"].(string),
+		UpdatedAt:   node.Props["updatedAt"].(string),
+	}
+	if email, ok := node.Props["email"].(string); ok {
+		user.Email = email
+	}
+	if passwordHash, ok := node.Props["passwordHash"].(string); ok {
+		user.PasswordHash = passwordHash
+	}
+	if avatarKey, ok := node.Props["avatarKey"].(string); ok {
+		user.AvatarKey = avatarKey
+	}
+	if issuer, ok := node.Props["oidcIssuer"].(string); ok {
+		user.OIDCIssuer = issuer
+		user.OIDCSubject, _ = node.Props["oidcSubject"].(string)
+	}
+	// Users registered before roles were introduced have none
+	if role, ok := node.Props["role"].(string); ok {
+		user.Role = model.Role(role)
+	}
+	return &user
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gocql/gocql"
+
+	apimodel "ndb/server/app/models"
+	logrepo "ndb/server/repositories/log"
+	"ndb/server/services/auth"
+)
+
+// SystemActor is recorded as the actor of changes made by background workers.
+const SystemActor = "system"
+
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+// Action names a kind of change, prefixed with the type of the changed entity.
+type Action string
+
+const (
+	ThreadCreated Action = "thread.create"
+	ThreadUpdated Action = "thread.update"
+	ThreadDeleted Action = "thread.delete"
+
+	TagUpdated Action = "tag.update"
+	TagMerged  Action = "tag.merge"
+
+	PostCreated          Action = "post.create"
+	PostUpdated          Action = "post.update"
+	PostDeleted          Action = "post.delete"
+	PostRestored         Action = "post.restore"
+	PostPublished        Action = "post.publish"
+	PostUnpublished      Action = "post.unpublish"
+	PostPurged           Action = "post.purge"
+	PostRevisionRestored Action = "post.restore_revision"
+	PostAuthorInvited    Action = "post.invite_author"
+	PostInviteAccepted   Action = "post.accept_invitation"
+	PostInviteDeclined   Action = "post.decline_invitation"
+
+	CommentCreated Action = "comment.create"
+	CommentUpdated Action = "comment.update"
+	CommentDeleted Action = "comment.delete"
+)
+
+// EntityType returns the type of the entity the action changes.
+func (a Action) EntityType() string {
+	entityType, _, _ := strings.Cut(string(a), ".")
+	return entityType
+}
+
+type Store interface {
+	InsertAuditEvent(ctx context.Context, event *logrepo.AuditEvent)
+	ListAuditEvents(ctx context.Context, query *logrepo.AuditQuery) ([]*logrepo.AuditEvent, []byte, error)
+}
+
+type Service struct {
+	store Store
+	log   *slog.Logger
+}
+
+func NewService(store Store, log *slog.Logger) *Service {
+	return &Service{
+		store: store,
+		log:   log,
+	}
+}
+
+// Record queues an event of the action on the entity. The actor is the principal of the request, or the system
+// for changes made without one. Events are written in batches, so a failure doesn't fail the change itself.
+func (s *Service) Record(ctx context.Context, action Action, entityID string, before, after map[string]string) {
+	event := &logrepo.AuditEvent{
+		EventID:    gocql.TimeUUID(),
+		EntityType: action.EntityType(),
+		EntityID:   entityID,
+		Action:     string(action),
+		ActorID:    SystemActor,
+		ActorName:  SystemActor,
+		RequestID:  middleware.GetReqID(ctx),
+		Before:     before,
+		After:      after,
+	}
+	if principal, ok := auth.PrincipalFrom(ctx); ok {
+		event.ActorID = principal.UserID
+		event.ActorName = principal.Username
+	}
+
+	s.store.InsertAuditEvent(ctx, event)
+}
+
+// List returns a page of events of the entity, the actor or the actor on the entity, the newest first.
+// Only admins can read the audit trail.
+func (s *Service) List(ctx context.Context, entityID, actorID string, limit int, after string) (*apimodel.AuditPage, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || !principal.Can(auth.ReadLogs) {
+		return nil, fmt.Errorf("%w: only admins can read the audit trail", ErrForbidden)
+	}
+
+	if entityID == "" && actorID == "" {
+		return nil, fmt.Errorf("%w: entity or actor is required", ErrInvalidInput)
+	}
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidInput)
+	}
+
+	query := &logrepo.AuditQuery{EntityID: entityID, ActorID: actorID, Limit: limit}
+	if after != "" {
+		var err error
+		query.PageState, err = base64.RawURLEncoding.DecodeString(after)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cursor: %v", ErrInvalidInput, err)
+		}
+	}
+
+	events, pageState, err := s.store.ListAuditEvents(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &apimodel.AuditPage{Events: make([]*apimodel.AuditEvent, len(events))}
+	for i, event := range events {
+		page.Events[i] = &apimodel.AuditEvent{
+			EventID:    event.EventID.String(),
+			OccurredAt: event.OccurredAt().Format(time.RFC3339),
+			EntityType: event.EntityType,
+			EntityID:   event.EntityID,
+			Action:     event.Action,
+			ActorID:    event.ActorID,
+			ActorName:  event.ActorName,
+			RequestID:  event.RequestID,
+			Before:     event.Before,
+			After:      event.After,
+		}
+	}
+	if pageState != nil {
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(pageState)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+)
+
+const (
+	// APIKeyPrefix tells API keys apart from access tokens
+	APIKeyPrefix = "ndb_"
+
+	maxAPIKeyNameLength = 100
+	// touchInterval limits how often the last-used timestamp of a key is written
+	touchInterval = time.Minute
+)
+
+// CreateAPIKey issues a key for the user of the request. The key itself is returned only here, the store
+// keeps its hash. Keys can't be created with another API key.
+func (s *Service) CreateAPIKey(
+	ctx context.Context,
+	data *apimodel.CreateAPIKeyRequest,
+) (string, string, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok || principal.APIKeyID != "" {
+		return "", "", fmt.Errorf("%w: API keys are created by logged in users only", ErrForbidden)
+	}
+
+	name := strings.TrimSpace(data.Name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return "", "", fmt.Errorf("%w: name must have 1 to %d bytes", ErrInvalidInput, maxAPIKeyNameLength)
+	}
+
+	if len(data.Scopes) == 0 {
+		return "", "", fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	for _, name := range data.Scopes {
+		if _, ok := ParseScope(name); !ok {
+			return "", "", fmt.Errorf("%w: unknown scope %q", ErrInvalidInput, name)
+		}
+	}
+
+	var expiresAt time.Time
+	if data.ExpiresAt != "" {
+		var err error
+		expiresAt, err = time.Parse(time.RFC3339, data.ExpiresAt)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: expires_at must be a RFC3339 date", ErrInvalidInput)
+		}
+		if !expiresAt.After(time.Now()) {
+			return "", "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidInput)
+		}
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		return "", "", err
+	}
+	key := APIKeyPrefix + secret
+
+	keyID, err := s.store.CreateAPIKey(
+		ctx,
+		model.APIKeyFrom(principal.UserID, name, hashToken(key), data.Scopes, expiresAt),
+	)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return "", "", fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error creating API key", slog.Any("error", err), slog.Any("user_id", principal.UserID))
+		return "", "", err
+	}
+
+	return keyID, key, nil
+}
+
+// ListAPIKeys returns the keys of the user of the request. With all, admins get keys of every user.
+func (s *Service) ListAPIKeys(ctx context.Context, all bool) ([]*apimodel.APIKey, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	userID := principal.UserID
+	if all {
+		if !principal.Can(ManageUsers) {
+			return nil, fmt.Errorf("%w: only admins can list all API keys", ErrForbidden)
+		}
+		userID = ""
+	}
+
+	keys, err := s.store.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*apimodel.APIKey, len(keys))
+	for i, key := range keys {
+		resp[i] = &apimodel.APIKey{
+			KeyID:      key.KeyID,
+			UserID:     key.UserID,
+			Name:       key.Name,
+			Scopes:     key.Scopes,
+			CreatedAt:  key.CreatedAt,
+			ExpiresAt:  key.ExpiresAt,
+			LastUsedAt: key.LastUsedAt,
+			RevokedAt:  key.RevokedAt,
+		}
+	}
+
+	return resp, nil
+}
+
+// RevokeAPIKey stops the key from working. Users revoke their own keys, admins any key. With asAdmin set,
+// the caller has to be an admin, whoever owns the key.
+func (s *Service) RevokeAPIKey(ctx context.Context, keyID string, asAdmin bool) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+	if asAdmin && !principal.Can(ManageUsers) {
+		return fmt.Errorf("%w: only admins can revoke API keys of other users", ErrForbidden)
+	}
+
+	key, err := s.store.GetAPIKey(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error getting API key", slog.Any("error", err), slog.Any("key_id", keyID))
+		return err
+	}
+
+	if principal.UserID != key.UserID && !principal.Can(ManageUsers) {
+		return fmt.Errorf("%w: can't revoke API key %s", ErrForbidden, keyID)
+	}
+
+	if err = s.store.RevokeAPIKey(ctx, keyID, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey returns the principal of the key owner limited to the key scopes. The role of the owner
+// is read on every request, so key permissions follow role changes immediately.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	apiKey, owner, err := s.store.GetAPIKeyByHash(ctx, hashToken(key))
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		s.log.ErrorContext(ctx, "Error getting API key", slog.Any("error", err))
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !apiKey.Active(now) {
+		return nil, ErrInvalidToken
+	}
+
+	if apiKey.LastUsedAt < now.Add(-touchInterval).Format(time.RFC3339) {
+		// Failing to record the usage shouldn't fail the request
+		_ = s.store.TouchAPIKey(ctx, apiKey.KeyID, now.Format(time.RFC3339))
+	}
+
+	principal := principalOf(owner)
+	principal.APIKeyID = apiKey.KeyID
+	for _, name := range apiKey.Scopes {
+		if scope, ok := ParseScope(name); ok {
+			principal.Scopes = append(principal.Scopes, scope)
+		}
+	}
+
+	return principal, nil
+}
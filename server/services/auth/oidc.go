@@ -0,0 +1,345 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/oauth2"
+
+	"ndb/server/config"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+)
+
+const (
+	oidcLoginKeyPrefix = "auth:oidc:"
+	maxUsernameLength  = 32
+	// usernameAttempts is the number of random suffixes tried when the username from the claims is taken
+	usernameAttempts = 5
+)
+
+var (
+	ErrOIDCDisabled = errors.New("OIDC login is not configured")
+
+	invalidUsernameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+	// roleRank orders roles from the most privileged, when claims map to several roles the first one wins
+	roleRank = []model.Role{model.RoleAdmin, model.RoleEditor, model.RoleAuthor, model.RoleReader}
+)
+
+// oidcLogin is stored in redis under the state of a started login until the provider redirects back.
+type oidcLogin struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+// oidcClaims are the standard claims used to provision users.
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+// oidcProvider discovers the provider on first use, so the server starts even when the provider is down.
+type oidcProvider struct {
+	cfg *config.OIDC
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func (p *oidcProvider) enabled() bool {
+	return p.cfg.Issuer != ""
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover OIDC provider: %v", err)
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+
+	return p.oauth, p.verifier, nil
+}
+
+// StartOIDCLogin returns the URL of the provider login page. The state, nonce and PKCE verifier of the login
+// are kept in redis until the provider redirects back.
+func (s *Service) StartOIDCLogin(ctx context.Context) (string, error) {
+	if !s.oidc.enabled() {
+		return "", ErrOIDCDisabled
+	}
+
+	oauth, _, err := s.oidc.discover(ctx)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error discovering OIDC provider", slog.Any("error", err))
+		return "", err
+	}
+
+	state, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	login := &oidcLogin{Verifier: oauth2.GenerateVerifier(), Nonce: nonce}
+
+	data, err := json.Marshal(login)
+	if err != nil {
+		return "", err
+	}
+	if err = s.redisClient.Set(ctx, oidcLoginKeyPrefix+hashToken(state), data, s.oidc.cfg.LoginTimeout).Err(); err != nil {
+		s.log.ErrorContext(ctx, "Failed to store OIDC login", slog.Any("error", err))
+		return "", fmt.Errorf("failed to store OIDC login: %v", err)
+	}
+
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(login.Verifier)), nil
+}
+
+// FinishOIDCLogin exchanges the authorization code for an ID token and issues a pair of tokens to the user it
+// identifies. Users are provisioned on their first login, the role claim updates their role on every login.
+func (s *Service) FinishOIDCLogin(ctx context.Context, state, code string) (*Tokens, error) {
+	if !s.oidc.enabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	data, err := s.redisClient.GetDel(ctx, oidcLoginKeyPrefix+hashToken(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: unknown or expired login state", ErrInvalidToken)
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get OIDC login", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get OIDC login: %v", err)
+	}
+
+	var login oidcLogin
+	if err = json.Unmarshal(data, &login); err != nil {
+		return nil, fmt.Errorf("failed to decode OIDC login: %v", err)
+	}
+
+	oauth, verifier, err := s.oidc.discover(ctx)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error discovering OIDC provider", slog.Any("error", err))
+		return nil, err
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to exchange authorization code: %v", ErrInvalidToken, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: provider returned no ID token", ErrInvalidToken)
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, fmt.Errorf("%w: ID token nonce doesn't match", ErrInvalidToken)
+	}
+
+	var claims oidcClaims
+	var rawClaims map[string]any
+	if err = idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err = idToken.Claims(&rawClaims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	user, err := s.provisionUser(ctx, idToken.Issuer, idToken.Subject, &claims, s.mapRole(rawClaims))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, principalOf(user))
+}
+
+// provisionUser returns the user of the identity, creating it on the first login. An existing user with the same
+// verified email is linked to the identity instead, so accounts with local passwords keep their posts.
+func (s *Service) provisionUser(
+	ctx context.Context,
+	issuer, subject string,
+	claims *oidcClaims,
+	role model.Role,
+) (*model.User, error) {
+	user, err := s.store.GetUserByIdentity(ctx, issuer, subject)
+	if err == nil {
+		return s.syncRole(ctx, user, role)
+	}
+	if !errors.Is(err, posts.ErrNotFound) {
+		s.log.ErrorContext(ctx, "Error getting user by identity", slog.Any("error", err))
+		return nil, err
+	}
+
+	email := strings.ToLower(claims.Email)
+	if email != "" {
+		user, err = s.store.GetUserByEmail(ctx, email)
+		switch {
+		case err == nil && claims.EmailVerified:
+			now := time.Now().UTC().Format(time.RFC3339)
+			if err = s.store.LinkIdentity(ctx, user.UserID, issuer, subject, now); err != nil {
+				if errors.Is(err, posts.ErrConflict) {
+					return nil, fmt.Errorf("%w: %v", ErrForbidden, err)
+				}
+				return nil, err
+			}
+			s.log.InfoContext(ctx, "Linked OIDC identity to user", slog.Any("user_id", user.UserID))
+			return s.syncRole(ctx, user, role)
+		case err == nil:
+			return nil, fmt.Errorf("%w: email %s is taken and not verified by the provider", ErrForbidden, email)
+		case !errors.Is(err, posts.ErrNotFound):
+			s.log.ErrorContext(ctx, "Error getting user by email", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
+	if role == "" {
+		role = s.defaultRole
+	}
+
+	base := usernameFrom(claims)
+	displayName := claims.Name
+	if displayName == "" {
+		displayName = base
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	user = &model.User{
+		Username:    base,
+		Email:       email,
+		Role:        role,
+		DisplayName: displayName,
+		OIDCIssuer:  issuer,
+		OIDCSubject: subject,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for attempt := 0; ; attempt++ {
+		user.UserID, err = s.store.CreateUser(ctx, user)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, posts.ErrConflict) || attempt == usernameAttempts {
+			s.log.ErrorContext(ctx, "Error provisioning user", slog.Any("error", err), slog.Any("username", base))
+			return nil, err
+		}
+
+		user.Username, err = withSuffix(base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.log.InfoContext(ctx, "Provisioned user from OIDC", slog.Any("user_id", user.UserID))
+	return user, nil
+}
+
+// syncRole saves the role mapped from the claims. Without a mapped role the user keeps the current one,
+// so roles given by admins are not lost.
+func (s *Service) syncRole(ctx context.Context, user *model.User, role model.Role) (*model.User, error) {
+	if role == "" || role == user.Role {
+		return user, nil
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := s.store.UpdateUser(ctx, user); err != nil {
+		s.log.ErrorContext(ctx, "Error updating role", slog.Any("error", err), slog.Any("user_id", user.UserID))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// mapRole returns the most privileged role the values of the role claim map to, or empty role when none does.
+func (s *Service) mapRole(claims map[string]any) model.Role {
+	var values []string
+	switch claim := claims[s.oidc.cfg.RoleClaim].(type) {
+	case string:
+		values = []string{claim}
+	case []any:
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	var roles []model.Role
+	for _, value := range values {
+		if role, ok := model.ParseRole(s.oidc.cfg.Roles[value]); ok {
+			roles = append(roles, role)
+		}
+	}
+
+	for _, role := range roleRank {
+		if slices.Contains(roles, role) {
+			return role
+		}
+	}
+	return ""
+}
+
+// usernameFrom derives a valid username from the preferred username or the email of the user.
+func usernameFrom(claims *oidcClaims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	name = strings.Trim(invalidUsernameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > maxUsernameLength {
+		name = name[:maxUsernameLength]
+	}
+	if len(name) < 3 {
+		name = "user"
+	}
+	return name
+}
+
+func withSuffix(username string) (string, error) {
+	b := make([]byte, 2)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate username: %v", err)
+	}
+
+	suffix := "-" + hex.EncodeToString(b)
+	if len(username)+len(suffix) > maxUsernameLength {
+		username = username[:maxUsernameLength-len(suffix)]
+	}
+	return username + suffix, nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+
+	"ndb/server/repositories/posts/model"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated user making the request. The role is the one the user had when
+// the access token was issued. Requests authenticated with an API key are limited to the scopes of the key.
+type Principal struct {
+	UserID   string     `json:"user_id"`
+	Username string     `json:"username"`
+	Role     model.Role `json:"role"`
+	APIKeyID string     `json:"api_key_id,omitempty"`
+	Scopes   []Scope    `json:"scopes,omitempty"`
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal of the request, if it is authenticated.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
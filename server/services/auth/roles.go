@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"slices"
+
+	"ndb/server/repositories/posts/model"
+)
+
+// Permission is an action checked against the role of the principal.
+type Permission string
+
+const (
+	CreateThread     Permission = "threads:create"
+	ManageThreads    Permission = "threads:manage"
+	ManageTags       Permission = "tags:manage"
+	CreatePost       Permission = "posts:create"
+	EditAnyPost      Permission = "posts:edit_any"
+	ViewTrash        Permission = "posts:view_trash"
+	Comment          Permission = "comments:create"
+	ModerateComments Permission = "comments:moderate"
+	EditProfile      Permission = "users:edit_profile"
+	ManageUsers      Permission = "users:manage"
+	ReadLogs         Permission = "logs:read"
+)
+
+var rolePermissions = map[model.Role][]Permission{
+	model.RoleAdmin: {
+		CreateThread, ManageThreads, ManageTags, CreatePost, EditAnyPost, ViewTrash, Comment, ModerateComments,
+		EditProfile, ManageUsers, ReadLogs,
+	},
+	model.RoleEditor: {
+		CreateThread, ManageThreads, CreatePost, EditAnyPost, ViewTrash, Comment, ModerateComments, EditProfile,
+	},
+	model.RoleAuthor: {CreatePost, Comment, EditProfile},
+	model.RoleReader: {Comment, EditProfile},
+}
+
+// Scope limits what can be done with an API key, on top of the role of its owner.
+type Scope string
+
+const (
+	ScopePostsWrite   Scope = "posts:write"
+	ScopeThreadsWrite Scope = "threads:write"
+	ScopeLogsRead     Scope = "logs:read"
+)
+
+var scopePermissions = map[Scope][]Permission{
+	ScopePostsWrite:   {CreatePost, EditAnyPost},
+	ScopeThreadsWrite: {CreateThread, ManageThreads, ManageTags},
+	ScopeLogsRead:     {ReadLogs},
+}
+
+// ParseScope returns the scope with the given name.
+func ParseScope(name string) (Scope, bool) {
+	scope := Scope(name)
+	_, ok := scopePermissions[scope]
+	return scope, ok
+}
+
+// Can reports whether the role of the principal grants the permission and, for API keys, whether
+// one of the key scopes covers it.
+func (p *Principal) Can(permission Permission) bool {
+	if !slices.Contains(rolePermissions[p.Role], permission) {
+		return false
+	}
+
+	if p.APIKeyID == "" {
+		return true
+	}
+
+	for _, scope := range p.Scopes {
+		if slices.Contains(scopePermissions[scope], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanEditPost reports whether the principal may change the post the users with editorIDs are allowed to edit.
+// Authors change their own posts only, editors and admins change any post.
+func (p *Principal) CanEditPost(editorIDs ...string) bool {
+	return p.Can(EditAnyPost) || (p.Can(CreatePost) && slices.Contains(editorIDs, p.UserID))
+}
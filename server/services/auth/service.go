@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/crypto/bcrypt"
+
+	"ndb/server/config"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+)
+
+const (
+	accessKeyPrefix  = "auth:access:"
+	refreshKeyPrefix = "auth:refresh:"
+	tokenBytes       = 32
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrNotFound           = errors.New("not found")
+	ErrForbidden          = errors.New("forbidden")
+)
+
+type Store interface {
+	GetUser(ctx context.Context, userID string) (*model.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*model.User, error)
+	CreateUser(ctx context.Context, user *model.User) (string, error)
+	UpdateUser(ctx context.Context, user *model.User) error
+	LinkIdentity(ctx context.Context, userID, issuer, subject, updatedAt string) error
+
+	CreateAPIKey(ctx context.Context, key *model.APIKey) (string, error)
+	GetAPIKey(ctx context.Context, keyID string) (*model.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, *model.User, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID, revokedAt string) error
+	TouchAPIKey(ctx context.Context, keyID, usedAt string) error
+}
+
+// Tokens is a pair of tokens issued on login. The access token authenticates requests until it expires,
+// the refresh token can be exchanged once for a new pair.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// session is stored in redis under the hash of a token. It points at the other token of the pair,
+// so both are revoked together.
+type session struct {
+	Principal
+	Pair string `json:"pair"`
+}
+
+// Service issues opaque tokens and keeps the sessions they belong to in redis. Only hashes of the tokens
+// are stored, so a dump of redis can't be used to impersonate users.
+type Service struct {
+	redisClient *redis.Client
+	store       Store
+	log         *slog.Logger
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+
+	passwordLogin bool
+	defaultRole   model.Role
+	oidc          *oidcProvider
+}
+
+func NewService(
+	redisClient *redis.Client,
+	store Store,
+	cfg *config.Auth,
+	oidcCfg *config.OIDC,
+	log *slog.Logger,
+) (*Service, error) {
+	defaultRole, ok := model.ParseRole(cfg.DefaultRole)
+	if !ok {
+		return nil, fmt.Errorf("unknown default role: %s", cfg.DefaultRole)
+	}
+
+	return &Service{
+		redisClient:   redisClient,
+		store:         store,
+		log:           log,
+		accessTTL:     cfg.AccessTokenTTL,
+		refreshTTL:    cfg.RefreshTokenTTL,
+		passwordLogin: cfg.PasswordLogin,
+		defaultRole:   defaultRole,
+		oidc:          &oidcProvider{cfg: oidcCfg},
+	}, nil
+}
+
+// Login checks the password of the user and issues a new pair of tokens.
+func (s *Service) Login(ctx context.Context, username, password string) (*Tokens, error) {
+	if !s.passwordLogin {
+		return nil, fmt.Errorf("%w: password login is disabled", ErrForbidden)
+	}
+
+	user, err := s.store.GetUserByUsername(ctx, strings.ToLower(username))
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		s.log.ErrorContext(ctx, "Error getting user", slog.Any("error", err))
+		return nil, err
+	}
+
+	if err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.issue(ctx, principalOf(user))
+}
+
+// Refresh exchanges the refresh token for a new pair of tokens. The old pair is revoked. The user is loaded
+// again, so role changes apply to the new access token.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	data, err := s.redisClient.GetDel(ctx, refreshKeyPrefix+hashToken(refreshToken)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get refresh token", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get refresh token: %v", err)
+	}
+
+	var sess session
+	if err = json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %v", err)
+	}
+
+	if err = s.redisClient.Del(ctx, accessKeyPrefix+sess.Pair).Err(); err != nil {
+		s.log.ErrorContext(ctx, "Failed to revoke access token", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to revoke access token: %v", err)
+	}
+
+	user, err := s.store.GetUser(ctx, sess.UserID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		s.log.ErrorContext(ctx, "Error getting user", slog.Any("error", err), slog.Any("user_id", sess.UserID))
+		return nil, err
+	}
+
+	return s.issue(ctx, principalOf(user))
+}
+
+// Logout revokes the access token and the refresh token issued with it.
+func (s *Service) Logout(ctx context.Context, accessToken string) error {
+	key := accessKeyPrefix + hashToken(accessToken)
+	sess, err := s.getSession(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err = s.redisClient.Del(ctx, key, refreshKeyPrefix+sess.Pair).Err(); err != nil {
+		s.log.ErrorContext(ctx, "Failed to revoke tokens", slog.Any("error", err))
+		return fmt.Errorf("failed to revoke tokens: %v", err)
+	}
+
+	return nil
+}
+
+// Authenticate returns the principal the access token was issued to.
+func (s *Service) Authenticate(ctx context.Context, accessToken string) (*Principal, error) {
+	sess, err := s.getSession(ctx, accessKeyPrefix+hashToken(accessToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return &sess.Principal, nil
+}
+
+func (s *Service) issue(ctx context.Context, principal *Principal) (*Tokens, error) {
+	accessToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	accessHash, refreshHash := hashToken(accessToken), hashToken(refreshToken)
+
+	access, err := json.Marshal(&session{Principal: *principal, Pair: refreshHash})
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := json.Marshal(&session{Principal: *principal, Pair: accessHash})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, accessKeyPrefix+accessHash, access, s.accessTTL)
+		pipe.Set(ctx, refreshKeyPrefix+refreshHash, refresh, s.refreshTTL)
+		return nil
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to store tokens", slog.Any("error", err), slog.Any("user_id", principal.UserID))
+		return nil, fmt.Errorf("failed to store tokens: %v", err)
+	}
+
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.accessTTL,
+	}, nil
+}
+
+func (s *Service) getSession(ctx context.Context, key string) (*session, error) {
+	data, err := s.redisClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get session", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get session: %v", err)
+	}
+
+	var sess session
+	if err = json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %v", err)
+	}
+
+	return &sess, nil
+}
+
+func principalOf(user *model.User) *Principal {
+	return &Principal{UserID: user.UserID, Username: user.Username, Role: user.Role}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
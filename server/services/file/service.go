@@ -63,6 +63,52 @@ func (s *Service) GetFile(
 	return readCloser, nil
 }
 
+func (s *Service) DeleteFile(
+	ctx context.Context,
+	contentFile string,
+) error {
+	err := s.s3Client.Delete(ctx, contentFile)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Could not delete file from S3",
+			slog.Any("error", err),
+			slog.Any("file_name", contentFile),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// GetRenderedFile returns the file content converted by render, e.g. markdown into HTML.
+func (s *Service) GetRenderedFile(
+	ctx context.Context,
+	contentFile string,
+	render func(content []byte) ([]byte, error),
+) ([]byte, error) {
+	rc, err := s.GetFile(ctx, contentFile)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to read file",
+			slog.Any("error", err),
+			slog.Any("file_name", contentFile),
+		)
+		return nil, err
+	}
+
+	return render(content)
+}
+
+const renderedKeyPrefix = "rendered:"
+
 type CachedService struct {
 	redisClient *redis.Client
 	base        *Service
@@ -83,6 +129,11 @@ func NewCachedService(s3Client *awsS3.Client, cfg *config.Redis, log *slog.Logge
 	}
 }
 
+// Client returns the redis client backing the cache, so other services can share the connection pool.
+func (c *CachedService) Client() *redis.Client {
+	return c.redisClient
+}
+
 func (c *CachedService) InsertFile(
 	ctx context.Context,
 	fileName string,
@@ -161,6 +212,62 @@ func (c *CachedService) GetFile(ctx context.Context, fileName string) (io.ReadCl
 	return io.NopCloser(bytes.NewReader(val)), nil
 }
 
+// GetRenderedFile returns the file content converted by render. The rendered content is cached next to
+// the raw one, file names are never reused for different content, so it does not have to be invalidated.
+func (c *CachedService) GetRenderedFile(ctx context.Context, fileName string, render func(content []byte) ([]byte, error)) ([]byte, error) {
+	key := renderedKeyPrefix + fileName
+
+	val, err := c.redisClient.Get(ctx, key).Bytes()
+	if err == nil {
+		return val, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		c.log.ErrorContext(
+			ctx,
+			"Failed to retrieve rendered file from redis cache",
+			slog.Any("error", err),
+			slog.Any("file_name", fileName),
+		)
+		return nil, fmt.Errorf("failed to retrieve rendered file from redis cache: %v", err)
+	}
+
+	rc, err := c.GetFile(ctx, fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := render(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.set(ctx, key, rendered); err != nil {
+		return nil, err
+	}
+
+	return rendered, nil
+}
+
+func (c *CachedService) DeleteFile(ctx context.Context, fileName string) error {
+	err := c.evict(ctx, fileName)
+	if err != nil {
+		return err
+	}
+
+	err = c.evict(ctx, renderedKeyPrefix+fileName)
+	if err != nil {
+		return err
+	}
+
+	return c.base.DeleteFile(ctx, fileName)
+}
+
 func (c *CachedService) set(ctx context.Context, fileName string, file []byte) error {
 	err := c.redisClient.Set(ctx, fileName, file, c.ttl).Err()
 	if err != nil {
@@ -174,3 +281,17 @@ func (c *CachedService) set(ctx context.Context, fileName string, file []byte) e
 	}
 	return nil
 }
+
+func (c *CachedService) evict(ctx context.Context, fileName string) error {
+	err := c.redisClient.Del(ctx, fileName).Err()
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Failed to evict redis cache",
+			slog.Any("error", err),
+			slog.Any("file_name", fileName),
+		)
+		return fmt.Errorf("failed to evict redis cache: %v", err)
+	}
+	return nil
+}
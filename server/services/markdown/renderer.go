@@ -0,0 +1,117 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	converter = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithASTTransformers(util.Prioritized(headingAnchors{}, 100)),
+		),
+	)
+
+	// textParser parses markdown without transformers, which would add text not present in the source
+	textParser = goldmark.New(goldmark.WithExtensions(extension.GFM, extension.Footnote)).Parser()
+
+	policy = newPolicy()
+)
+
+// ToHTML renders GitHub flavored markdown to HTML safe to embed in a page.
+// Headings get IDs and a self link, so sections of a post can be linked to.
+func ToHTML(source []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := converter.Convert(source, &buf); err != nil {
+		return nil, fmt.Errorf("failed to render markdown: %v", err)
+	}
+
+	return policy.SanitizeBytes(buf.Bytes()), nil
+}
+
+// PlainText extracts the text of the markdown document without formatting, e.g. to index it for search.
+func PlainText(source []byte) string {
+	doc := textParser.Parse(text.NewReader(source))
+
+	var buf strings.Builder
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if node.Type() == ast.TypeBlock {
+				buf.WriteByte('\n')
+			}
+			return ast.WalkContinue, nil
+		}
+
+		switch n := node.(type) {
+		case *ast.Text:
+			buf.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(n.Value)
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				buf.Write(segment.Value(source))
+			}
+		case *ast.HTMLBlock, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// newPolicy extends the policy for user generated content with attributes used by GFM task lists,
+// footnotes and heading anchors.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(false)
+	p.RequireNoFollowOnFullyQualifiedLinks(true)
+	p.AllowAttrs("class").
+		Matching(regexp.MustCompile(`^(anchor|footnote-ref|footnote-backref|footnotes)$`)).
+		OnElements("a", "div")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// headingAnchors appends a link pointing at the heading itself to every heading with an ID.
+type headingAnchors struct{}
+
+func (headingAnchors) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		id, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+
+		anchor := ast.NewLink()
+		anchor.Destination = append([]byte("#"), id.([]byte)...)
+		anchor.SetAttributeString("class", []byte("anchor"))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, anchor)
+
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -0,0 +1,79 @@
+package posts
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/audit"
+)
+
+// auditBodyLength is the length of comment bodies kept in audit summaries
+const auditBodyLength = 200
+
+type AuditLog interface {
+	Record(ctx context.Context, action audit.Action, entityID string, before, after map[string]string)
+}
+
+func threadSummary(thread *model.Thread) map[string]string {
+	return map[string]string{
+		"name":        thread.Name,
+		"description": thread.Description,
+		"tags":        strings.Join(thread.Tags, ","),
+		"archived":    strconv.FormatBool(thread.Archived),
+	}
+}
+
+func tagSummary(tag *model.Tag) map[string]string {
+	return map[string]string{
+		"display_name": tag.DisplayName,
+		"parent":       tag.Parent,
+		"aliases":      strings.Join(tag.Aliases, ","),
+	}
+}
+
+func postSummary(post *model.Post) map[string]string {
+	return map[string]string{
+		"title":        post.Title,
+		"thread_id":    post.ThreadID,
+		"author_id":    post.UserID,
+		"status":       string(post.Status),
+		"publish_at":   post.PublishAt,
+		"content_file": post.ContentFile,
+		"tags":         strings.Join(post.Tags, ","),
+	}
+}
+
+func revisionSummary(post *model.Post, number int) map[string]string {
+	return map[string]string{
+		"revision":     strconv.Itoa(number),
+		"content_file": post.ContentFile,
+	}
+}
+
+func commentSummary(comment *model.Comment) map[string]string {
+	body := comment.Body
+	if len(body) > auditBodyLength {
+		end := auditBodyLength
+		for end > 0 && !utf8.RuneStart(body[end]) {
+			end--
+		}
+		body = body[:end] + "…"
+	}
+
+	return map[string]string{
+		"post_id":   comment.PostID,
+		"parent_id": comment.ParentID,
+		"author_id": comment.UserID,
+		"body":      body,
+	}
+}
+
+func invitationSummary(userID string, role model.AuthorRole) map[string]string {
+	return map[string]string{
+		"user_id": userID,
+		"role":    string(role),
+	}
+}
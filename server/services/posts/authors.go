@@ -0,0 +1,154 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/audit"
+	"ndb/server/services/auth"
+)
+
+// InviteAuthor invites the user to become a co-author of the post. Authors of the post invite co-authors,
+// editors and admins invite them to any post.
+func (s *Service) InviteAuthor(ctx context.Context, postID string, data *apimodel.InviteAuthorRequest) error {
+	if data.UserID == "" {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidInput)
+	}
+
+	role := model.AuthorRoleAuthor
+	if data.Role != "" {
+		var ok bool
+		if role, ok = model.ParseAuthorRole(data.Role); !ok {
+			return fmt.Errorf("%w: unknown author role %s", ErrInvalidInput, data.Role)
+		}
+	}
+
+	principal, err := s.authorizeInvitation(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.InviteAuthor(ctx, &model.Invitation{
+		PostID:    postID,
+		UserID:    data.UserID,
+		Role:      role,
+		InvitedBy: principal.UserID,
+		InvitedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, posts.ErrNotFound):
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		case errors.Is(err, posts.ErrConflict):
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return err
+	}
+
+	s.audit.Record(ctx, audit.PostAuthorInvited, postID, nil, invitationSummary(data.UserID, role))
+	return nil
+}
+
+// AcceptInvitation makes the principal of the request a co-author of the post it was invited to.
+func (s *Service) AcceptInvitation(ctx context.Context, postID string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	acceptedAt := time.Now().UTC().Format(time.RFC3339)
+	role, err := s.store.AcceptInvitation(ctx, postID, principal.UserID, acceptedAt)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error accepting invitation", slog.Any("error", err), slog.Any("post_id", postID))
+		return err
+	}
+
+	s.audit.Record(ctx, audit.PostInviteAccepted, postID, nil, invitationSummary(principal.UserID, role))
+	return nil
+}
+
+// DeclineInvitation removes the invitation of the principal of the request to the post.
+func (s *Service) DeclineInvitation(ctx context.Context, postID string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	if err := s.store.DeclineInvitation(ctx, postID, principal.UserID); err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error declining invitation", slog.Any("error", err), slog.Any("post_id", postID))
+		return err
+	}
+
+	s.audit.Record(ctx, audit.PostInviteDeclined, postID, invitationSummary(principal.UserID, ""), nil)
+	return nil
+}
+
+// ListInvitations returns pending invitations of the principal of the request.
+func (s *Service) ListInvitations(ctx context.Context) ([]*apimodel.Invitation, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	invitations, err := s.store.ListInvitations(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*apimodel.Invitation, len(invitations))
+	for i, invitation := range invitations {
+		resp[i] = &apimodel.Invitation{
+			PostID:    invitation.PostID,
+			PostTitle: invitation.PostTitle,
+			Role:      string(invitation.Role),
+			InvitedBy: invitation.InvitedBy,
+			InvitedAt: invitation.InvitedAt,
+		}
+	}
+
+	return resp, nil
+}
+
+// addAuthors adds authors to the posts, in their order.
+func (s *Service) addAuthors(ctx context.Context, posts ...*apimodel.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]string, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.PostID
+	}
+
+	authors, err := s.store.GetAuthors(ctx, postIDs)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error getting authors", slog.Any("error", err))
+		return err
+	}
+
+	for _, post := range posts {
+		post.Authors = make([]*apimodel.Author, 0, len(authors[post.PostID]))
+		for _, author := range authors[post.PostID] {
+			post.Authors = append(post.Authors, &apimodel.Author{
+				UserID:      author.UserID,
+				Username:    author.Username,
+				DisplayName: author.DisplayName,
+				Role:        string(author.Role),
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/auth"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// authorize returns the principal of the request when its role grants the permission.
+func authorize(ctx context.Context, permission auth.Permission) (*auth.Principal, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	if !principal.Can(permission) {
+		return nil, fmt.Errorf("%w: %s is not allowed for role %s", ErrForbidden, permission, principal.Role)
+	}
+
+	return principal, nil
+}
+
+// authorizePost checks that the principal of the request is one of the authors allowed to change the post,
+// or an editor or admin, whatever the status of the post is.
+func (s *Service) authorizePost(ctx context.Context, postID string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	authors, err := s.getPostAuthors(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	if !principal.CanEditPost(model.EditorIDs(authors)...) {
+		return fmt.Errorf("%w: user %s can't change post %s", ErrForbidden, principal.UserID, postID)
+	}
+
+	return nil
+}
+
+// authorizeInvitation checks that the principal of the request may invite co-authors of the post. Authors of
+// the post with the author role invite them, editors and admins manage authors of any post.
+func (s *Service) authorizeInvitation(ctx context.Context, postID string) (*auth.Principal, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	authors, err := s.getPostAuthors(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal.Can(auth.EditAnyPost) {
+		return principal, nil
+	}
+	for _, author := range authors {
+		if author.UserID == principal.UserID && author.Role == model.AuthorRoleAuthor && principal.Can(auth.CreatePost) {
+			return principal, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: user %s can't invite authors of post %s", ErrForbidden, principal.UserID, postID)
+}
+
+func (s *Service) getPostAuthors(ctx context.Context, postID string) ([]*model.Author, error) {
+	authors, err := s.store.GetPostAuthors(ctx, postID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
+		s.log.ErrorContext(ctx, "Error getting post authors", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	return authors, nil
+}
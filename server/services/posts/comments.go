@@ -0,0 +1,220 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/audit"
+	"ndb/server/services/auth"
+)
+
+const (
+	maxCommentLength = 10000
+
+	CommentsTree = "tree"
+	CommentsFlat = "flat"
+)
+
+func (s *Service) CreateComment(ctx context.Context, postID string, data *apimodel.CreateCommentRequest) (string, error) {
+	if _, err := authorize(ctx, auth.Comment); err != nil {
+		return "", err
+	}
+	if data.UserID == "" {
+		return "", fmt.Errorf("%w: user_id is required", ErrInvalidInput)
+	}
+	if err := validateCommentBody(data.Body); err != nil {
+		return "", err
+	}
+
+	comment := model.CommentFrom(postID, data)
+	commentID, err := s.store.CreateComment(ctx, comment)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return "", fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error creating comment", slog.Any("error", err), slog.Any("post_id", postID))
+		return "", err
+	}
+
+	s.audit.Record(ctx, audit.CommentCreated, commentID, nil, commentSummary(comment))
+	return commentID, nil
+}
+
+// ListComments returns a page of comments on the published post, the oldest first. In the tree view pages
+// contain top-level comments with all their replies nested, in the flat view every comment is a separate item.
+func (s *Service) ListComments(
+	ctx context.Context,
+	postID, view string,
+	limit int,
+	after string,
+) (*apimodel.CommentPage, error) {
+	if view == "" {
+		view = CommentsTree
+	}
+	if view != CommentsTree && view != CommentsFlat {
+		return nil, fmt.Errorf("%w: unknown comments view: %s", ErrInvalidInput, view)
+	}
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidInput)
+	}
+
+	page := &model.PageQuery{Limit: limit, Sort: model.SortCreated}
+	if after != "" {
+		var err error
+		page.After, err = model.DecodeCursor(after, model.SortCreated)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+
+	if _, err := s.getPost(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	c, err := s.store.ListComments(ctx, postID, view == CommentsTree, page)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error listing comments", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	resp := &apimodel.CommentPage{Comments: []*apimodel.Comment{}}
+	if len(c) > limit {
+		c = c[:limit]
+		resp.NextCursor = c[limit-1].Cursor().Encode()
+	}
+
+	for _, comment := range c {
+		resp.Comments = append(resp.Comments, toAPIComment(comment))
+	}
+
+	if view == CommentsFlat || len(c) == 0 {
+		return resp, nil
+	}
+
+	rootIDs := make([]string, len(c))
+	for i, comment := range c {
+		rootIDs[i] = comment.CommentID
+	}
+
+	replies, err := s.store.GetReplies(ctx, postID, rootIDs)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error getting replies", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	// Replies are ordered from the oldest, so a parent is always indexed before its replies
+	byID := make(map[string]*apimodel.Comment, len(resp.Comments)+len(replies))
+	for _, comment := range resp.Comments {
+		byID[comment.CommentID] = comment
+	}
+	for _, reply := range replies {
+		comment := toAPIComment(reply)
+		byID[comment.CommentID] = comment
+		if parent, ok := byID[comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		}
+	}
+
+	return resp, nil
+}
+
+// UpdateComment replaces the body of the comment. Only the author of the comment may edit it.
+func (s *Service) UpdateComment(ctx context.Context, commentID string, data *apimodel.UpdateCommentRequest) error {
+	if err := validateCommentBody(data.Body); err != nil {
+		return err
+	}
+
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || principal.UserID != comment.UserID || !principal.Can(auth.Comment) {
+		return fmt.Errorf("%w: only the author can edit comment %s", ErrForbidden, commentID)
+	}
+
+	err = s.store.UpdateComment(ctx, commentID, data.Body, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error updating comment", slog.Any("error", err), slog.Any("comment_id", commentID))
+		return err
+	}
+
+	before := commentSummary(comment)
+	comment.Body = data.Body
+	s.audit.Record(ctx, audit.CommentUpdated, commentID, before, commentSummary(comment))
+	return nil
+}
+
+// DeleteComment removes the content of the comment, replies to it are kept. Comments are deleted by their
+// authors or moderated by editors and admins.
+func (s *Service) DeleteComment(ctx context.Context, commentID string) error {
+	comment, err := s.getComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || !(principal.UserID == comment.UserID && principal.Can(auth.Comment) || principal.Can(auth.ModerateComments)) {
+		return fmt.Errorf("%w: can't delete comment %s", ErrForbidden, commentID)
+	}
+
+	err = s.store.DeleteComment(ctx, commentID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error deleting comment", slog.Any("error", err), slog.Any("comment_id", commentID))
+		return err
+	}
+
+	s.audit.Record(ctx, audit.CommentDeleted, commentID, commentSummary(comment), nil)
+	return nil
+}
+
+func (s *Service) getComment(ctx context.Context, commentID string) (*model.Comment, error) {
+	comment, err := s.store.GetComment(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error getting comment", slog.Any("error", err), slog.Any("comment_id", commentID))
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func validateCommentBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("%w: comment body is empty", ErrInvalidInput)
+	}
+	if len(body) > maxCommentLength {
+		return fmt.Errorf("%w: comment body is longer than %d bytes", ErrInvalidInput, maxCommentLength)
+	}
+	return nil
+}
+
+func toAPIComment(comment *model.Comment) *apimodel.Comment {
+	return &apimodel.Comment{
+		CommentID: comment.CommentID,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		UserID:    comment.UserID,
+		Body:      comment.Body,
+		Deleted:   comment.DeletedAt != "",
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
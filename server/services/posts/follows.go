@@ -0,0 +1,55 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/auth"
+)
+
+// Follow adds the thread or the author to the feed of the principal of the request.
+func (s *Service) Follow(ctx context.Context, target model.FollowTarget, targetID string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	if target == model.FollowUser && targetID == principal.UserID {
+		return fmt.Errorf("%w: users can't follow themselves", ErrInvalidInput)
+	}
+
+	since := time.Now().UTC().Format(time.RFC3339)
+	if err := s.store.Follow(ctx, principal.UserID, target, targetID, since); err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Unfollow removes the thread or the author from the feed of the principal of the request.
+func (s *Service) Unfollow(ctx context.Context, target model.FollowTarget, targetID string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	return s.store.Unfollow(ctx, principal.UserID, target, targetID)
+}
+
+// Feed returns a page of published posts from threads and by authors the principal of the request follows.
+func (s *Service) Feed(ctx context.Context, limit int, sort, after string) (*apimodel.PostPage, error) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: not authenticated", ErrForbidden)
+	}
+
+	return s.listPosts(ctx, &model.PostFilter{FollowerID: principal.UserID}, limit, sort, after)
+}
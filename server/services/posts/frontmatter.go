@@ -0,0 +1,125 @@
+package posts
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	apimodel "ndb/server/app/models"
+)
+
+const (
+	yamlDelimiter = "---"
+	tomlDelimiter = "+++"
+)
+
+// frontMatter holds the post metadata written at the top of the markdown file.
+type frontMatter struct {
+	Title       string    `yaml:"title" toml:"title"`
+	Thread      string    `yaml:"thread" toml:"thread"`
+	Description string    `yaml:"description" toml:"description"`
+	Tags        []string  `yaml:"tags" toml:"tags"`
+	Date        time.Time `yaml:"date" toml:"date"`
+	CoverImage  string    `yaml:"cover_image" toml:"cover_image"`
+}
+
+// parseFrontMatter splits the YAML (---) or TOML (+++) front matter block from the markdown content.
+// Content without front matter is returned as it is, together with empty metadata.
+func parseFrontMatter(content []byte) (*frontMatter, []byte, error) {
+	meta := &frontMatter{}
+
+	firstLine, rest := cutLine(content)
+	delimiter := string(bytes.TrimSpace(firstLine))
+	if delimiter != yamlDelimiter && delimiter != tomlDelimiter {
+		return meta, content, nil
+	}
+
+	blockStart := len(content) - len(rest)
+	for len(rest) > 0 {
+		lineStart := len(content) - len(rest)
+
+		var line []byte
+		line, rest = cutLine(rest)
+		if string(bytes.TrimSpace(line)) != delimiter {
+			continue
+		}
+
+		block := content[blockStart:lineStart]
+		var err error
+		if delimiter == yamlDelimiter {
+			err = yaml.Unmarshal(block, meta)
+		} else {
+			err = toml.Unmarshal(block, meta)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: malformed front matter: %v", ErrInvalidInput, err)
+		}
+
+		return meta, bytes.TrimLeft(rest, "\r\n"), nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: front matter is not closed with %s", ErrInvalidInput, delimiter)
+}
+
+// cutLine returns the first line of the content, without its line ending, and the content after it.
+func cutLine(content []byte) ([]byte, []byte) {
+	line, rest, _ := bytes.Cut(content, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r")), rest
+}
+
+// applyToCreate fills the post fields which were not set in the request, so form fields override the front matter.
+func (m *frontMatter) applyToCreate(data *apimodel.CreatePostRequest) {
+	m.fill(postFields{
+		title:       &data.Title,
+		thread:      &data.Thread,
+		description: &data.Description,
+		coverImage:  &data.CoverImage,
+		tags:        &data.Tags,
+		publishDate: &data.PublishDate,
+	})
+}
+
+// applyToUpdate fills the update fields which were not set in the request, the same way as applyToCreate.
+func (m *frontMatter) applyToUpdate(data *apimodel.UpdatePostRequest) {
+	m.fill(postFields{
+		title:       &data.Title,
+		thread:      &data.Thread,
+		description: &data.Description,
+		coverImage:  &data.CoverImage,
+		tags:        &data.Tags,
+		publishDate: &data.PublishDate,
+	})
+}
+
+// postFields points at the request fields which can be provided by front matter.
+type postFields struct {
+	title       *string
+	thread      *string
+	description *string
+	coverImage  *string
+	tags        *[]string
+	publishDate *time.Time
+}
+
+func (m *frontMatter) fill(fields postFields) {
+	*fields.title = firstNonEmpty(*fields.title, m.Title)
+	*fields.thread = firstNonEmpty(*fields.thread, m.Thread)
+	*fields.description = firstNonEmpty(*fields.description, m.Description)
+	*fields.coverImage = firstNonEmpty(*fields.coverImage, m.CoverImage)
+	if len(*fields.tags) == 0 {
+		*fields.tags = m.Tags
+	}
+	if fields.publishDate.IsZero() {
+		*fields.publishDate = m.Date
+	}
+}
+
+func firstNonEmpty(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
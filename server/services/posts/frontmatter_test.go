@@ -0,0 +1,152 @@
+package posts
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	apimodel "ndb/server/app/models"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantMeta    *frontMatter
+		wantContent string
+		wantErr     bool
+	}{
+		{
+			name:        "no front matter",
+			content:     "# Title\n\nBody\n",
+			wantMeta:    &frontMatter{},
+			wantContent: "# Title\n\nBody\n",
+		},
+		{
+			name: "yaml",
+			content: "---\ntitle: Hello\nthread: go\ntags: [a, b]\ndate: 2024-05-01T10:00:00Z\n" +
+				"cover_image: cover.png\n---\n\n# Body\n",
+			wantMeta: &frontMatter{
+				Title:      "Hello",
+				Thread:     "go",
+				Tags:       []string{"a", "b"},
+				Date:       time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				CoverImage: "cover.png",
+			},
+			wantContent: "# Body\n",
+		},
+		{
+			name:        "toml",
+			content:     "+++\ntitle = \"Hello\"\ndescription = \"About\"\n+++\nBody",
+			wantMeta:    &frontMatter{Title: "Hello", Description: "About"},
+			wantContent: "Body",
+		},
+		{
+			name:        "crlf line endings",
+			content:     "---\r\ntitle: Hello\r\n---\r\nBody\r\n",
+			wantMeta:    &frontMatter{Title: "Hello"},
+			wantContent: "Body\r\n",
+		},
+		{
+			name:        "empty block",
+			content:     "---\n---\nBody",
+			wantMeta:    &frontMatter{},
+			wantContent: "Body",
+		},
+		{
+			name:        "delimiter later in the content",
+			content:     "Body\n---\ntitle: Hello\n---\n",
+			wantMeta:    &frontMatter{},
+			wantContent: "Body\n---\ntitle: Hello\n---\n",
+		},
+		{
+			name:    "not closed",
+			content: "---\ntitle: Hello\nBody",
+			wantErr: true,
+		},
+		{
+			name:    "closed with other delimiter",
+			content: "---\ntitle: Hello\n+++\nBody",
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			content: "---\ntitle: [Hello\n---\nBody",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, content, err := parseFrontMatter([]byte(tt.content))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Errorf("parseFrontMatter() error = %v, want %v", err, ErrInvalidInput)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFrontMatter() error = %v", err)
+			}
+			if !reflect.DeepEqual(meta, tt.wantMeta) {
+				t.Errorf("parseFrontMatter() meta = %+v, want %+v", meta, tt.wantMeta)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("parseFrontMatter() content = %q, want %q", content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestFrontMatterApplyToUpdate(t *testing.T) {
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	meta := &frontMatter{
+		Title:       "Front matter title",
+		Thread:      "front-matter-thread",
+		Description: "Front matter description",
+		Tags:        []string{"go"},
+		Date:        date,
+		CoverImage:  "cover.png",
+	}
+
+	tests := []struct {
+		name string
+		data *apimodel.UpdatePostRequest
+		want *apimodel.UpdatePostRequest
+	}{
+		{
+			name: "empty request",
+			data: &apimodel.UpdatePostRequest{},
+			want: &apimodel.UpdatePostRequest{
+				Title:       "Front matter title",
+				Thread:      "front-matter-thread",
+				Description: "Front matter description",
+				Tags:        []string{"go"},
+				CoverImage:  "cover.png",
+				PublishDate: date,
+			},
+		},
+		{
+			name: "request fields take precedence",
+			data: &apimodel.UpdatePostRequest{Title: "Form title", Tags: []string{"rust"}},
+			want: &apimodel.UpdatePostRequest{
+				Title:       "Form title",
+				Thread:      "front-matter-thread",
+				Description: "Front matter description",
+				Tags:        []string{"rust"},
+				CoverImage:  "cover.png",
+				PublishDate: date,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta.applyToUpdate(tt.data)
+			if !reflect.DeepEqual(tt.data, tt.want) {
+				t.Errorf("applyToUpdate() = %+v, want %+v", tt.data, tt.want)
+			}
+		})
+	}
+}
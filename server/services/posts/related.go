@@ -0,0 +1,61 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/services/auth"
+)
+
+// RelatedPosts returns up to limit published posts to read after the published post, the most related first.
+func (s *Service) RelatedPosts(ctx context.Context, postID string, limit int) ([]*apimodel.RelatedPost, error) {
+	related, err := s.store.RelatedPosts(ctx, postID, limit)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		return nil, err
+	}
+
+	resp := make([]*apimodel.RelatedPost, len(related))
+	relatedPosts := make([]*apimodel.Post, len(related))
+	for i, hit := range related {
+		post := hit.Post
+		relatedPosts[i] = toAPIPost(post)
+
+		reasons := make([]string, 0, 4)
+		for _, reason := range hit.Reasons() {
+			reasons = append(reasons, string(reason))
+		}
+		resp[i] = &apimodel.RelatedPost{
+			Post:       relatedPosts[i],
+			Score:      hit.Score,
+			Reasons:    reasons,
+			SharedTags: hit.SharedTags,
+			CoReaders:  hit.CoReaders,
+		}
+	}
+	s.addPendingViews(ctx, relatedPosts...)
+	if err = s.addAuthors(ctx, relatedPosts...); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// recordRead remembers that the principal of the request read the post, so readers link related posts.
+// Anonymous reads are not recorded and failures don't fail the request.
+func (s *Service) recordRead(ctx context.Context, postID, readAt string) {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return
+	}
+
+	if err := s.store.RecordRead(ctx, principal.UserID, postID, readAt); err != nil {
+		s.log.ErrorContext(ctx, "Error recording post read", slog.Any("error", err), slog.Any("post_id", postID))
+	}
+}
@@ -0,0 +1,302 @@
+package posts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/audit"
+	"ndb/server/services/markdown"
+)
+
+var ErrConflict = errors.New("conflict")
+
+func (s *Service) ListRevisions(ctx context.Context, postID string) ([]*apimodel.Revision, error) {
+	post, err := s.getPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionHistory(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*apimodel.Revision, 0, len(revisions))
+	for _, revision := range revisions {
+		resp = append(resp, &apimodel.Revision{
+			Number:      revision.Number,
+			ContentFile: revision.ContentFile,
+			ContentHash: revision.ContentHash,
+			AuthorID:    revision.AuthorID,
+			CreatedAt:   revision.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// DiffRevisions returns the unified diff of the post content between two revisions.
+func (s *Service) DiffRevisions(ctx context.Context, postID string, from, to int) (string, error) {
+	post, err := s.getPost(ctx, postID)
+	if err != nil {
+		return "", err
+	}
+
+	fromRevision, fromContent, err := s.revisionContent(ctx, post, from)
+	if err != nil {
+		return "", err
+	}
+
+	toRevision, toContent, err := s.revisionContent(ctx, post, to)
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(fromContent)),
+		B:        difflib.SplitLines(string(toContent)),
+		FromFile: fmt.Sprintf("r%d", fromRevision.Number),
+		FromDate: fromRevision.CreatedAt,
+		ToFile:   fmt.Sprintf("r%d", toRevision.Number),
+		ToDate:   toRevision.CreatedAt,
+		Context:  3,
+	})
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error creating diff", slog.Any("error", err), slog.Any("post_id", postID))
+		return "", err
+	}
+
+	return diff, nil
+}
+
+// RestoreRevision makes the content of the given revision current again. The history is never rewritten,
+// the restored content is recorded as a new revision instead.
+func (s *Service) RestoreRevision(ctx context.Context, postID string, number int, authorID string) (int, error) {
+	post, err := s.getEditablePost(ctx, postID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, content, err := s.revisionContent(ctx, post, number)
+	if err != nil {
+		return 0, err
+	}
+
+	if authorID == "" {
+		authorID = post.UserID
+	}
+
+	before := map[string]string{"content_file": post.ContentFile}
+	restored, err := s.addRevision(ctx, post, content, authorID)
+	if err != nil {
+		return 0, err
+	}
+
+	after := revisionSummary(post, restored)
+	after["restored_from"] = strconv.Itoa(number)
+	s.audit.Record(ctx, audit.PostRevisionRestored, postID, before, after)
+	return restored, nil
+}
+
+// addRevision uploads the content under a new versioned file and records it as the current revision.
+// Content equal to the current revision is not stored again.
+func (s *Service) addRevision(ctx context.Context, post *model.Post, content []byte, authorID string) (int, error) {
+	baseline, revision, changed, err := s.prepareRevision(ctx, post, content, authorID)
+	if err != nil {
+		return 0, err
+	}
+	if !changed {
+		return revision.Number, nil
+	}
+
+	if err = s.store.AddRevision(ctx, post.PostID, baseline, revision, markdown.PlainText(content)); err != nil {
+		s.discardRevision(ctx, revision)
+		if errors.Is(err, posts.ErrConflict) {
+			return 0, fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		s.log.ErrorContext(ctx, "Error adding revision", slog.Any("error", err), slog.Any("post_id", post.PostID))
+		return 0, err
+	}
+
+	post.ContentFile = revision.ContentFile
+	post.UpdatedAt = revision.CreatedAt
+
+	return revision.Number, nil
+}
+
+// prepareRevision uploads the content under a new versioned file and returns the revision to record. When the
+// content is equal to the current revision, that revision is returned unchanged and nothing is uploaded. Posts
+// created before revisions were tracked also get the baseline revision to record before the new one.
+func (s *Service) prepareRevision(
+	ctx context.Context,
+	post *model.Post,
+	content []byte,
+	authorID string,
+) (baseline, revision *model.Revision, changed bool, err error) {
+	revisions, err := s.store.GetRevisions(ctx, post.PostID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error getting revisions", slog.Any("error", err), slog.Any("post_id", post.PostID))
+		return nil, nil, false, err
+	}
+
+	var latest *model.Revision
+	if len(revisions) > 0 {
+		latest = revisions[0]
+	} else {
+		if baseline, err = s.baselineRevision(ctx, post); err != nil {
+			return nil, nil, false, err
+		}
+		latest = baseline
+	}
+
+	hash := contentHash(content)
+	if latest.ContentHash == hash {
+		return nil, latest, false, nil
+	}
+
+	revision = model.RevisionFrom(post, latest.Number+1, hash, authorID)
+	if err = s.fileManager.InsertFile(ctx, revision.ContentFile, content); err != nil {
+		s.log.ErrorContext(ctx, "Error inserting revision file", slog.Any("error", err), slog.Any("post_id", post.PostID))
+		return nil, nil, false, err
+	}
+
+	return baseline, revision, true, nil
+}
+
+// discardRevision removes the file of the revision which was not recorded, failures are only logged.
+func (s *Service) discardRevision(ctx context.Context, revision *model.Revision) {
+	if err := s.fileManager.DeleteFile(ctx, revision.ContentFile); err != nil {
+		s.log.WarnContext(
+			ctx,
+			"Error removing unused revision file",
+			slog.Any("error", err),
+			slog.Any("file_name", revision.ContentFile),
+		)
+	}
+}
+
+// revisionHistory returns revisions of the post, the newest first. Posts created before revisions were tracked
+// have their current content as the only revision, it is recorded by the first change of the post.
+func (s *Service) revisionHistory(ctx context.Context, post *model.Post) ([]*model.Revision, error) {
+	revisions, err := s.store.GetRevisions(ctx, post.PostID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error getting revisions", slog.Any("error", err), slog.Any("post_id", post.PostID))
+		return nil, err
+	}
+
+	if len(revisions) > 0 {
+		return revisions, nil
+	}
+
+	baseline, err := s.baselineRevision(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	return []*model.Revision{baseline}, nil
+}
+
+// baselineRevision describes the current content of the post which has no revisions yet as its first revision.
+func (s *Service) baselineRevision(ctx context.Context, post *model.Post) (*model.Revision, error) {
+	content, err := s.readFile(ctx, post.ContentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Revision{
+		Number:      1,
+		ContentFile: post.ContentFile,
+		ContentHash: contentHash(content),
+		AuthorID:    post.UserID,
+		CreatedAt:   post.UpdatedAt,
+	}, nil
+}
+
+func (s *Service) revisionContent(ctx context.Context, post *model.Post, number int) (*model.Revision, []byte, error) {
+	revision, err := s.store.GetRevision(ctx, post.PostID, number)
+	if errors.Is(err, posts.ErrNotFound) && number == 1 {
+		// The first revision of posts without revisions is their current content
+		var history []*model.Revision
+		if history, err = s.revisionHistory(ctx, post); err == nil {
+			if revision = history[len(history)-1]; revision.Number != 1 {
+				err = posts.ErrNotFound
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: revision %d", ErrNotFound, number)
+		}
+		s.log.ErrorContext(ctx, "Error getting revision", slog.Any("error", err), slog.Any("post_id", post.PostID))
+		return nil, nil, err
+	}
+
+	content, err := s.readFile(ctx, revision.ContentFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return revision, content, nil
+}
+
+func (s *Service) getPost(ctx context.Context, postID string) (*model.Post, error) {
+	post, err := s.store.GetPost(ctx, postID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
+		s.log.ErrorContext(ctx, "Error getting post", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// getEditablePost returns the post whatever its status is, the caller must be allowed to edit it.
+func (s *Service) getEditablePost(ctx context.Context, postID string) (*model.Post, error) {
+	if err := s.authorizePost(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	post, err := s.store.GetEditablePost(ctx, postID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
+		s.log.ErrorContext(ctx, "Error getting post", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	return post, nil
+}
+
+func (s *Service) readFile(ctx context.Context, fileName string) ([]byte, error) {
+	rc, err := s.fileManager.GetFile(ctx, fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading file content", slog.Any("error", err), slog.Any("file_name", fileName))
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func contentHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,162 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts/model"
+)
+
+const (
+	snippetLength = 200
+	// snippetLead is the length of the text shown before the first match
+	snippetLead = 60
+)
+
+// luceneSpecial matches characters with special meaning in the Lucene query syntax used by the full-text index.
+var luceneSpecial = regexp.MustCompile(`[+\-&|!(){}\[\]^"~*?:\\/]`)
+
+// luceneOperators are the words which Lucene treats as boolean operators, they are case-sensitive.
+var luceneOperators = map[string]bool{"AND": true, "OR": true, "NOT": true}
+
+// Search returns a page of published posts matching all or some of the words in query, the best matches first.
+// Empty threadID and tag don't filter the results.
+func (s *Service) Search(
+	ctx context.Context,
+	query, threadID, tag string,
+	limit int,
+	after string,
+) (*apimodel.SearchPage, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: query is empty", ErrInvalidInput)
+	}
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidInput)
+	}
+
+	slug := model.TagSlug(tag)
+	if tag != "" && slug == "" {
+		return nil, fmt.Errorf("%w: tag %q is not valid", ErrInvalidInput, tag)
+	}
+
+	search := &model.SearchQuery{
+		Query:    luceneQuery(terms),
+		ThreadID: threadID,
+		Tag:      slug,
+		Page:     &model.PageQuery{Limit: limit, Sort: model.SortRelevance},
+	}
+	if after != "" {
+		var err error
+		search.Page.After, err = model.DecodeCursor(after, model.SortRelevance)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+
+	hits, err := s.store.SearchPosts(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &apimodel.SearchPage{Results: []*apimodel.SearchResult{}}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		page.NextCursor = hits[limit-1].Cursor().Encode()
+	}
+
+	highlight := highlightPattern(terms)
+	for _, hit := range hits {
+		post := hit.Post
+		page.Results = append(page.Results, &apimodel.SearchResult{
+			Post:    toAPIPost(post),
+			Score:   hit.Score,
+			Snippet: snippet(post.BodyText, highlight),
+		})
+	}
+
+	posts := make([]*apimodel.Post, len(page.Results))
+	for i, result := range page.Results {
+		posts[i] = result.Post
+	}
+	s.addPendingViews(ctx, posts...)
+	if err = s.addAuthors(ctx, posts...); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// luceneQuery escapes the terms and searches for them in all indexed properties, matches in the title weigh the most.
+// Operator words are lower-cased, so they are searched for as any other term.
+func luceneQuery(terms []string) string {
+	escaped := make([]string, len(terms))
+	for i, term := range terms {
+		if luceneOperators[term] {
+			term = strings.ToLower(term)
+		}
+		escaped[i] = luceneSpecial.ReplaceAllString(term, `\$0`)
+	}
+	q := strings.Join(escaped, " ")
+
+	return fmt.Sprintf("title:(%[1]s)^3 name:(%[1]s)^2 bodyText:(%[1]s)", q)
+}
+
+func highlightPattern(terms []string) *regexp.Regexp {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+
+	return regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+}
+
+// snippet cuts the part of the text around the first match and wraps matches in <mark>. The rest of the text
+// is HTML escaped, so the snippet can be embedded in a page as it is.
+func snippet(text string, highlight *regexp.Regexp) string {
+	if text == "" {
+		return ""
+	}
+
+	start := 0
+	if match := highlight.FindStringIndex(text); match != nil && match[0] > snippetLead {
+		start = match[0] - snippetLead
+	}
+	end := min(start+snippetLength, len(text))
+
+	// Don't split multibyte characters
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	fragment := text[start:end]
+
+	var buf strings.Builder
+	if start > 0 {
+		buf.WriteString("…")
+	}
+
+	last := 0
+	for _, match := range highlight.FindAllStringIndex(fragment, -1) {
+		buf.WriteString(html.EscapeString(fragment[last:match[0]]))
+		buf.WriteString("<mark>")
+		buf.WriteString(html.EscapeString(fragment[match[0]:match[1]]))
+		buf.WriteString("</mark>")
+		last = match[1]
+	}
+	buf.WriteString(html.EscapeString(fragment[last:]))
+
+	if end < len(text) {
+		buf.WriteString("…")
+	}
+
+	return buf.String()
+}
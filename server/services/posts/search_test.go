@@ -0,0 +1,47 @@
+package posts
+
+import "testing"
+
+func TestLuceneQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		want  string
+	}{
+		{
+			name:  "single term",
+			terms: []string{"go"},
+			want:  `title:(go)^3 name:(go)^2 bodyText:(go)`,
+		},
+		{
+			name:  "many terms",
+			terms: []string{"neo4j", "search"},
+			want:  `title:(neo4j search)^3 name:(neo4j search)^2 bodyText:(neo4j search)`,
+		},
+		{
+			name:  "special characters",
+			terms: []string{"c++", "a:b", `"quoted"`, "(x)"},
+			want: `title:(c\+\+ a\:b \"quoted\" \(x\))^3 name:(c\+\+ a\:b \"quoted\" \(x\))^2 ` +
+				`bodyText:(c\+\+ a\:b \"quoted\" \(x\))`,
+		},
+		{
+			name:  "operators",
+			terms: []string{"rock", "AND", "roll", "OR", "NOT", "&&", "||"},
+			want: `title:(rock and roll or not \&\& \|\|)^3 name:(rock and roll or not \&\& \|\|)^2 ` +
+				`bodyText:(rock and roll or not \&\& \|\|)`,
+		},
+		{
+			name:  "lower-case operator words",
+			terms: []string{"and", "Or"},
+			want:  `title:(and Or)^3 name:(and Or)^2 bodyText:(and Or)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := luceneQuery(tt.terms); got != tt.want {
+				t.Errorf("luceneQuery() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,13 +8,24 @@ import (
 	"io"
 	"log/slog"
 	"mime/multipart"
+	"slices"
+	"time"
+
+	"github.com/go-redis/redis/v8"
 
 	apimodel "ndb/server/app/models"
+	"ndb/server/config"
 	"ndb/server/repositories/posts"
 	"ndb/server/repositories/posts/model"
+	"ndb/server/services/audit"
+	"ndb/server/services/auth"
+	"ndb/server/services/markdown"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+)
 
 type FileService interface {
 	InsertFile(
@@ -26,23 +37,59 @@ type FileService interface {
 		ctx context.Context,
 		fileName string,
 	) (io.ReadCloser, error)
+	DeleteFile(
+		ctx context.Context,
+		fileName string,
+	) error
+	GetRenderedFile(
+		ctx context.Context,
+		fileName string,
+		render func(content []byte) ([]byte, error),
+	) ([]byte, error)
+}
+
+type ViewCounter interface {
+	Record(ctx context.Context, postID, visitorID string) error
+	Pending(ctx context.Context, postIDs ...string) (map[string]int64, error)
 }
 
 type Service struct {
 	store       *posts.Store
 	log         *slog.Logger
 	fileManager FileService
+	views       ViewCounter
+	audit       AuditLog
+
+	redisClient      *redis.Client
+	tagUsageCacheTTL time.Duration
 }
 
-func NewService(fileManager FileService, store *posts.Store, log *slog.Logger) *Service {
+func NewService(
+	fileManager FileService,
+	store *posts.Store,
+	views ViewCounter,
+	audit AuditLog,
+	redisClient *redis.Client,
+	cfg *config.Posts,
+	log *slog.Logger,
+) *Service {
 	return &Service{
-		fileManager: fileManager,
-		store:       store,
-		log:         log,
+		fileManager:      fileManager,
+		store:            store,
+		views:            views,
+		audit:            audit,
+		redisClient:      redisClient,
+		tagUsageCacheTTL: cfg.TagUsageCacheTTL,
+		log:              log,
 	}
 }
 
+// CreateThread is allowed to editors and admins.
 func (s *Service) CreateThread(ctx context.Context, data *apimodel.CreateThreadRequest) (string, error) {
+	if _, err := authorize(ctx, auth.CreateThread); err != nil {
+		return "", err
+	}
+
 	thread := model.ThreadFrom(data)
 	threadID, err := s.store.CreateThread(ctx, thread)
 	if err != nil {
@@ -50,19 +97,14 @@ func (s *Service) CreateThread(ctx context.Context, data *apimodel.CreateThreadR
 		return "", err
 	}
 
+	s.audit.Record(ctx, audit.ThreadCreated, threadID, nil, threadSummary(thread))
 	return threadID, nil
 }
 
+// CreatePost stores the post and its markdown file. Front matter of the file provides defaults for the fields
+// missing in data and is stripped from the stored content.
 func (s *Service) CreatePost(ctx context.Context, file multipart.File, data *apimodel.CreatePostRequest) (string, error) {
-	// Create the Post object from the request data
-	post := model.PostFrom(data)
-	post.ContentFile = fmt.Sprintf("%s.md", uuid.New().String())
-
-	// Set the post ID and store the post metadata
-	var err error
-	post.PostID, err = s.store.CreatePost(ctx, post, data.Thread)
-	if err != nil {
-		s.log.ErrorContext(ctx, "Error creating post", slog.Any("error", err))
+	if _, err := authorize(ctx, auth.CreatePost); err != nil {
 		return "", err
 	}
 
@@ -73,8 +115,35 @@ func (s *Service) CreatePost(ctx context.Context, file multipart.File, data *api
 		return "", err
 	}
 
+	meta, contentBytes, err := parseFrontMatter(contentBytes)
+	if err != nil {
+		return "", err
+	}
+	meta.applyToCreate(data)
+
 	if len(contentBytes) == 0 {
-		return "", errors.New("tried to upload empty image")
+		return "", fmt.Errorf("%w: tried to upload empty file", ErrInvalidInput)
+	}
+	if data.Title == "" || data.Thread == "" {
+		return "", fmt.Errorf("%w: title and thread are required in form or front matter", ErrInvalidInput)
+	}
+
+	// Create the Post object from the request data
+	post := model.PostFrom(data)
+	post.ContentFile = fmt.Sprintf("%s.md", uuid.New().String())
+	post.BodyText = markdown.PlainText(contentBytes)
+
+	// Content of a new post is its first revision
+	revision := model.RevisionFrom(post, 1, contentHash(contentBytes), post.UserID)
+
+	// Set the post ID and store the post metadata
+	post.PostID, err = s.store.CreatePost(ctx, post, revision, data.Thread)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return "", fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error creating post", slog.Any("error", err))
+		return "", err
 	}
 
 	err = s.fileManager.InsertFile(ctx, post.ContentFile, contentBytes)
@@ -83,12 +152,334 @@ func (s *Service) CreatePost(ctx context.Context, file multipart.File, data *api
 		return "", err
 	}
 
+	s.audit.Record(ctx, audit.PostCreated, post.PostID, nil, postSummary(post))
 	return post.PostID, nil
 }
 
-func (s *Service) GetPostMetadata(ctx context.Context, postID string) (*apimodel.Post, error) {
+// UpdatePost replaces the post metadata present in data and, when file is not nil, adds a new revision of its content.
+// Front matter of the file is handled the same way as in CreatePost.
+func (s *Service) UpdatePost(
+	ctx context.Context,
+	postID string,
+	file multipart.File,
+	data *apimodel.UpdatePostRequest,
+) error {
+	post, err := s.getEditablePost(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	if !data.PublishAt.IsZero() && post.Status != model.StatusDraft && post.Status != model.StatusScheduled {
+		return fmt.Errorf("%w: only drafts and scheduled posts can be scheduled", ErrInvalidInput)
+	}
+
+	before := postSummary(post)
+	var (
+		baseline, revision *model.Revision
+		bodyText           string
+	)
+	if file != nil {
+		contentBytes, err := io.ReadAll(file)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Error reading file content", slog.Any("error", err))
+			return err
+		}
+
+		meta, contentBytes, err := parseFrontMatter(contentBytes)
+		if err != nil {
+			return err
+		}
+		meta.applyToUpdate(data)
+
+		if len(contentBytes) == 0 {
+			return fmt.Errorf("%w: tried to upload empty file", ErrInvalidInput)
+		}
+
+		author := data.UserID
+		if author == "" {
+			author = post.UserID
+		}
+
+		unrecorded, prepared, changed, err := s.prepareRevision(ctx, post, contentBytes, author)
+		if err != nil {
+			return err
+		}
+		if changed {
+			baseline, revision, bodyText = unrecorded, prepared, markdown.PlainText(contentBytes)
+		}
+	}
+
+	// Status is saved only when the post is scheduled, and only if nothing published it after it was read
+	var expectedStatus model.PostStatus
+	if !data.PublishAt.IsZero() {
+		expectedStatus = post.Status
+	}
+
+	post.ApplyUpdate(data)
+	// The revision is recorded together with the metadata, so a failed update leaves no partial changes
+	err = s.store.UpdatePost(ctx, post, data.Thread, baseline, revision, bodyText, expectedStatus)
+	if err != nil {
+		if revision != nil {
+			s.discardRevision(ctx, revision)
+		}
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		if errors.Is(err, posts.ErrConflict) {
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		s.log.ErrorContext(ctx, "Error updating post", slog.Any("error", err), slog.Any("post_id", postID))
+		return err
+	}
+	if revision != nil {
+		post.ContentFile = revision.ContentFile
+	}
+
+	s.audit.Record(ctx, audit.PostUpdated, postID, before, postSummary(post))
+	return nil
+}
+
+// DeletePost moves the post to trash, it stays there until restored or purged.
+func (s *Service) DeletePost(ctx context.Context, postID string) (string, error) {
+	post, err := s.getEditablePost(ctx, postID)
+	if err != nil {
+		return "", err
+	}
+
+	deletedAt := time.Now().UTC().Format(time.RFC3339)
+
+	err = s.store.SoftDeletePost(ctx, postID, deletedAt)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return "", fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
+		s.log.ErrorContext(ctx, "Error deleting post", slog.Any("error", err), slog.Any("post_id", postID))
+		return "", err
+	}
+
+	s.audit.Record(ctx, audit.PostDeleted, postID, postSummary(post), map[string]string{"deleted_at": deletedAt})
+	return deletedAt, nil
+}
+
+func (s *Service) RestorePost(ctx context.Context, postID string) error {
+	if err := s.authorizePost(ctx, postID); err != nil {
+		return err
+	}
+
+	post, err := s.store.GetDeletedPost(ctx, postID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: deleted post %s", ErrNotFound, postID)
+		}
+		s.log.ErrorContext(ctx, "Error getting deleted post", slog.Any("error", err), slog.Any("post_id", postID))
+		return err
+	}
+
+	restoredAt := time.Now().UTC().Format(time.RFC3339)
+	err = s.store.RestorePost(ctx, postID, restoredAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, posts.ErrNotFound):
+			return fmt.Errorf("%w: deleted post %s", ErrNotFound, postID)
+		case errors.Is(err, posts.ErrConflict):
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		s.log.ErrorContext(ctx, "Error restoring post", slog.Any("error", err), slog.Any("post_id", postID))
+		return err
+	}
+
+	s.audit.Record(ctx, audit.PostRestored, postID, postSummary(post), map[string]string{"restored_at": restoredAt})
+	return nil
+}
+
+// PublishPost publishes the draft or scheduled post right away, including posts which were unpublished before.
+// Authors publish their own posts, editors and admins any post.
+func (s *Service) PublishPost(ctx context.Context, postID string) error {
+	if err := s.authorizePost(ctx, postID); err != nil {
+		return err
+	}
+
+	previous, err := s.store.PublishPost(ctx, postID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error publishing post", slog.Any("error", err), slog.Any("post_id", postID))
+		return err
+	}
+
+	s.audit.Record(
+		ctx,
+		audit.PostPublished,
+		postID,
+		map[string]string{"status": string(previous)},
+		map[string]string{"status": string(model.StatusPublished)},
+	)
+	return nil
+}
+
+// UnpublishPost turns the post back into a draft. Authors unpublish their own posts, editors and admins any post.
+func (s *Service) UnpublishPost(ctx context.Context, postID string) error {
+	if err := s.authorizePost(ctx, postID); err != nil {
+		return err
+	}
+
+	err := s.store.UnpublishPost(ctx, postID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error unpublishing post", slog.Any("error", err), slog.Any("post_id", postID))
+		return err
+	}
+
+	s.audit.Record(
+		ctx,
+		audit.PostUnpublished,
+		postID,
+		map[string]string{"status": string(model.StatusPublished)},
+		map[string]string{"status": string(model.StatusDraft)},
+	)
+	return nil
+}
+
+func (s *Service) ListDeletedPosts(ctx context.Context) ([]*apimodel.Post, error) {
+	if _, err := authorize(ctx, auth.ViewTrash); err != nil {
+		return nil, err
+	}
+
+	p, err := s.store.GetDeletedPosts(ctx, "")
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error listing deleted posts", slog.Any("error", err))
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: trash is empty", ErrNotFound)
+	}
+
+	var posts []*apimodel.Post
+	for _, post := range p {
+		posts = append(posts, toAPIPost(post))
+	}
+	if err = s.addAuthors(ctx, posts...); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// PurgeDeletedPosts permanently removes posts, and their markdown files, which have been in trash longer than retention.
+// The post is removed before its files, so a failed purge never leaves a restorable post without content. Posts which
+// could not be removed are retried on the next purge, it returns the number of purged posts and an error when some
+// of them failed.
+func (s *Service) PurgeDeletedPosts(ctx context.Context, retention time.Duration) (int, error) {
+	deletedBefore := time.Now().UTC().Add(-retention).Format(time.RFC3339)
+
+	expired, err := s.store.GetDeletedPosts(ctx, deletedBefore)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error listing expired posts", slog.Any("error", err))
+		return 0, err
+	}
+
+	purged, failed := 0, 0
+	for _, post := range expired {
+		// Revisions are removed with the post, so their files are listed first
+		files, err := s.postFiles(ctx, post)
+		if err != nil {
+			s.log.ErrorContext(ctx, "Error listing post files", slog.Any("error", err), slog.Any("post_id", post.PostID))
+			failed++
+			continue
+		}
+
+		if err = s.store.DeletePost(ctx, post.PostID); err != nil {
+			s.log.ErrorContext(ctx, "Error purging post", slog.Any("error", err), slog.Any("post_id", post.PostID))
+			failed++
+			continue
+		}
+		s.audit.Record(ctx, audit.PostPurged, post.PostID, postSummary(post), nil)
+		purged++
+
+		// Files left behind are only unreachable, the post is already gone
+		for _, fileName := range files {
+			if err = s.fileManager.DeleteFile(ctx, fileName); err != nil {
+				s.log.ErrorContext(
+					ctx,
+					"Error deleting post file",
+					slog.Any("error", err),
+					slog.Any("post_id", post.PostID),
+					slog.Any("file_name", fileName),
+				)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return purged, fmt.Errorf("failed to purge %d of %d posts", failed, len(expired))
+	}
+	return purged, nil
+}
+
+// postFiles returns the current content file of the post and files of all its revisions.
+func (s *Service) postFiles(ctx context.Context, post *model.Post) ([]string, error) {
+	revisions, err := s.store.GetRevisions(ctx, post.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{post.ContentFile}
+	for _, revision := range revisions {
+		if !slices.Contains(files, revision.ContentFile) {
+			files = append(files, revision.ContentFile)
+		}
+	}
+
+	return files, nil
+}
+
+func (s *Service) ListDraftPosts(ctx context.Context, userID string) ([]*apimodel.Post, error) {
+	p, err := s.store.GetDraftPosts(ctx, userID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error listing draft posts", slog.Any("error", err), slog.Any("user_id", userID))
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: no drafts was found", ErrNotFound)
+	}
+
+	var posts []*apimodel.Post
+	for _, post := range p {
+		posts = append(posts, toAPIPost(post))
+	}
+	if err = s.addAuthors(ctx, posts...); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// PublishDuePosts publishes posts whose scheduled publish date has passed.
+func (s *Service) PublishDuePosts(ctx context.Context) (int, error) {
+	postIDs, err := s.store.PublishDuePosts(ctx, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, postID := range postIDs {
+		s.audit.Record(ctx, audit.PostPublished, postID, nil, map[string]string{"status": string(model.StatusPublished)})
+	}
+
+	return len(postIDs), nil
+}
+
+// GetPostMetadata returns the post and counts the view of the visitor.
+func (s *Service) GetPostMetadata(ctx context.Context, postID, visitorID string) (*apimodel.Post, error) {
 	post, err := s.store.GetPost(ctx, postID)
 	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: post %s", ErrNotFound, postID)
+		}
 		s.log.ErrorContext(
 			ctx,
 			"Error getting posts",
@@ -97,83 +488,185 @@ func (s *Service) GetPostMetadata(ctx context.Context, postID string) (*apimodel
 		)
 		return nil, err
 	}
-	post.ViewCount += 1
 
-	return &apimodel.Post{
-		PostID:      post.PostID,
-		UserID:      post.UserID,
-		ThreadID:    post.ThreadID,
-		Title:       post.Title,
-		ViewCount:   post.ViewCount,
-		ContentFile: post.ContentFile,
-		UpdatedAt:   post.UpdatedAt,
-	}, nil
+	if err = s.views.Record(ctx, postID, visitorID); err != nil {
+		s.log.ErrorContext(ctx, "Error recording post view", slog.Any("error", err), slog.Any("post_id", postID))
+	}
+	s.recordRead(ctx, postID, time.Now().UTC().Format(time.RFC3339))
+
+	resp := toAPIPost(post)
+	s.addPendingViews(ctx, resp)
+	if err = s.addAuthors(ctx, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// addPendingViews adds views which are not flushed to the store yet, so view counts are up-to-date.
+// On failure the persisted counts are left as they are.
+func (s *Service) addPendingViews(ctx context.Context, posts ...*apimodel.Post) {
+	postIDs := make([]string, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.PostID
+	}
+
+	pending, err := s.views.Pending(ctx, postIDs...)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error getting pending views", slog.Any("error", err))
+		return
+	}
+
+	for _, post := range posts {
+		post.ViewCount += int(pending[post.PostID])
+	}
 }
 
+// GetPostMarkdown returns the current content of the post stored in the file. Anyone reads published posts,
+// drafts and scheduled posts are read only by those allowed to edit them. Files of older revisions and of
+// posts in trash are not found.
 func (s *Service) GetPostMarkdown(ctx context.Context, contentFile string) (io.ReadCloser, error) {
+	post, err := s.store.GetPostByContentFile(ctx, contentFile)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: file %s", ErrNotFound, contentFile)
+		}
+		s.log.ErrorContext(ctx, "Error getting post", slog.Any("error", err), slog.Any("file_name", contentFile))
+		return nil, err
+	}
+
+	if post.Status != model.StatusPublished {
+		// Unpublished posts are hidden from those who can't edit them, as if they didn't exist
+		if err = s.authorizePost(ctx, post.PostID); err != nil {
+			if errors.Is(err, ErrForbidden) {
+				return nil, fmt.Errorf("%w: file %s", ErrNotFound, contentFile)
+			}
+			return nil, err
+		}
+	}
+
 	return s.fileManager.GetFile(ctx, contentFile)
 }
 
-func (s *Service) GetPostsWithLimit(ctx context.Context, limit int) (map[string][]*apimodel.Post, error) {
-	posts, err := s.store.GetPostsWithLimit(ctx, limit)
+// GetPostHTML returns the current content of the published post rendered to sanitized HTML.
+func (s *Service) GetPostHTML(ctx context.Context, postID string) ([]byte, error) {
+	post, err := s.getPost(ctx, postID)
 	if err != nil {
-		s.log.ErrorContext(
-			ctx,
-			"Error getting posts with limit",
-			slog.Any("error", err),
-			slog.Any("limit", limit),
-		)
 		return nil, err
 	}
 
-	postsResp := make(map[string][]*apimodel.Post, len(posts)) // Initialize the map
-	for key, items := range posts {
-		for _, p := range items {
-			// Check if the key already exists in the map
-			if _, exists := postsResp[key]; !exists {
-				// If not, initialize an empty array for this key
-				postsResp[key] = []*apimodel.Post{}
-			}
+	html, err := s.fileManager.GetRenderedFile(ctx, post.ContentFile, markdown.ToHTML)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error rendering post", slog.Any("error", err), slog.Any("post_id", postID))
+		return nil, err
+	}
+
+	return html, nil
+}
+
+// ListPosts returns a page of published posts from all threads.
+func (s *Service) ListPosts(ctx context.Context, limit int, sort, after string) (*apimodel.PostPage, error) {
+	return s.listPosts(ctx, &model.PostFilter{}, limit, sort, after)
+}
 
-			// Append the mapped post to the slice
-			postsResp[key] = append(postsResp[key], &apimodel.Post{
-				PostID:      p.PostID,
-				UserID:      p.UserID,
-				Title:       p.Title,
-				ThreadID:    p.ThreadID,
-				ViewCount:   p.ViewCount,
-				ContentFile: p.ContentFile,
-			})
+// ListUserPosts returns a page of published posts written by the user.
+func (s *Service) ListUserPosts(
+	ctx context.Context,
+	userID string,
+	limit int,
+	sort, after string,
+) (*apimodel.PostPage, error) {
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: user %s", ErrNotFound, userID)
 		}
+		s.log.ErrorContext(ctx, "Error getting user", slog.Any("error", err), slog.Any("user_id", userID))
+		return nil, err
 	}
 
-	return postsResp, nil
+	return s.listPosts(ctx, &model.PostFilter{AuthorID: userID}, limit, sort, after)
 }
 
-func (s *Service) ListPostInThread(ctx context.Context, threadID string) ([]*apimodel.Post, error) {
-	p, err := s.store.GetPostsInThread(ctx, threadID)
+// ListPostInThread returns a page of published posts from the thread.
+func (s *Service) ListPostInThread(
+	ctx context.Context,
+	threadID string,
+	limit int,
+	sort, after string,
+) (*apimodel.PostPage, error) {
+	page, err := s.listPosts(ctx, &model.PostFilter{ThreadID: threadID}, limit, sort, after)
 	if err != nil {
-		s.log.ErrorContext(ctx, "Error listing posts", slog.Any("error", err), slog.Any("thread_id", threadID))
 		return nil, err
 	}
 
-	if len(p) == 0 {
+	if len(page.Posts) == 0 && after == "" {
 		return nil, fmt.Errorf("%w: no posts was found", ErrNotFound)
 	}
 
-	var posts []*apimodel.Post
+	return page, nil
+}
+
+func (s *Service) listPosts(
+	ctx context.Context,
+	filter *model.PostFilter,
+	limit int,
+	sort, after string,
+) (*apimodel.PostPage, error) {
+	query, err := pageQuery(limit, sort, after)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.store.ListPosts(ctx, filter, query)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Error listing posts",
+			slog.Any("error", err),
+			slog.Any("thread_id", filter.ThreadID),
+			slog.Any("author_id", filter.AuthorID),
+			slog.Any("follower_id", filter.FollowerID),
+			slog.Any("limit", limit),
+		)
+		return nil, err
+	}
+
+	page := &apimodel.PostPage{Posts: []*apimodel.Post{}}
+	if len(p) > limit {
+		p = p[:limit]
+		page.NextCursor = model.CursorFor(p[limit-1], query.Sort).Encode()
+	}
+
 	for _, post := range p {
-		posts = append(posts, &apimodel.Post{
-			PostID:      post.PostID,
-			UserID:      post.UserID,
-			Title:       post.Title,
-			ThreadID:    post.ThreadID,
-			ViewCount:   post.ViewCount,
-			ContentFile: post.ContentFile,
-		})
+		page.Posts = append(page.Posts, toAPIPost(post))
+	}
+	s.addPendingViews(ctx, page.Posts...)
+	if err = s.addAuthors(ctx, page.Posts...); err != nil {
+		return nil, err
 	}
 
-	return posts, nil
+	return page, nil
+}
+
+func pageQuery(limit int, sort, after string) (*model.PageQuery, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidInput)
+	}
+
+	sortOrder, err := model.ParsePostSort(sort)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	query := &model.PageQuery{Limit: limit, Sort: sortOrder}
+	if after != "" {
+		query.After, err = model.DecodeCursor(after, sortOrder)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+
+	return query, nil
 }
 
 func (s *Service) ListThreads(ctx context.Context) ([]*apimodel.Thread, error) {
@@ -190,9 +683,11 @@ func (s *Service) ListThreads(ctx context.Context) ([]*apimodel.Thread, error) {
 	var threads []*apimodel.Thread
 	for _, thread := range t {
 		threads = append(threads, &apimodel.Thread{
-			ThreadID: thread.ThreadID,
-			Name:     thread.Name,
-			Tags:     thread.Tags,
+			ThreadID:    thread.ThreadID,
+			Name:        thread.Name,
+			Description: thread.Description,
+			Tags:        thread.Tags,
+			Archived:    thread.Archived,
 		})
 	}
 
@@ -212,3 +707,24 @@ func (s *Service) ListTags(ctx context.Context) ([]string, error) {
 
 	return tags, nil
 }
+
+// toAPIPost converts the stored post to its API representation, authors and pending views are added by the caller.
+func toAPIPost(post *model.Post) *apimodel.Post {
+	return &apimodel.Post{
+		PostID:       post.PostID,
+		ThreadID:     post.ThreadID,
+		ThreadName:   post.ThreadName,
+		Title:        post.Title,
+		ContentFile:  post.ContentFile,
+		UpdatedAt:    post.UpdatedAt,
+		DeletedAt:    post.DeletedAt,
+		Status:       string(post.Status),
+		PublishAt:    post.PublishAt,
+		ViewCount:    post.ViewCount,
+		CommentCount: post.CommentCount,
+		Description:  post.Description,
+		Tags:         post.Tags,
+		CoverImage:   post.CoverImage,
+		PublishDate:  post.PublishDate,
+	}
+}
@@ -0,0 +1,300 @@
+package posts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/audit"
+	"ndb/server/services/auth"
+)
+
+// tagUsageKeyPrefix prefixes keys of cached tag usage lists, followed by the sort order, limit and days
+const tagUsageKeyPrefix = "tags:usage:"
+
+// ListTaggedPosts returns a page of published posts tagged directly or through their thread, with the tag,
+// its aliases or descendant tags.
+func (s *Service) ListTaggedPosts(
+	ctx context.Context,
+	tag string,
+	limit int,
+	sort, after string,
+) (*apimodel.TaggedPostPage, error) {
+	query, err := pageQuery(limit, sort, after)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag = model.TagSlug(tag); tag == "" {
+		return nil, fmt.Errorf("%w: tag is empty", ErrInvalidInput)
+	}
+
+	tagged, err := s.store.ListTaggedPosts(ctx, tag, query)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error listing tagged posts", slog.Any("error", err), slog.Any("tag", tag))
+		return nil, err
+	}
+
+	if len(tagged) == 0 && after == "" {
+		return nil, fmt.Errorf("%w: no posts tagged %s", ErrNotFound, tag)
+	}
+
+	page := &apimodel.TaggedPostPage{Posts: []*apimodel.TaggedPost{}}
+	if len(tagged) > limit {
+		tagged = tagged[:limit]
+		page.NextCursor = model.CursorFor(tagged[limit-1].Post, query.Sort).Encode()
+	}
+
+	taggedPosts := make([]*apimodel.Post, len(tagged))
+	for i, hit := range tagged {
+		post := hit.Post
+		taggedPosts[i] = toAPIPost(post)
+		page.Posts = append(page.Posts, &apimodel.TaggedPost{Post: taggedPosts[i], Match: string(hit.Match)})
+	}
+	s.addPendingViews(ctx, taggedPosts...)
+	if err = s.addAuthors(ctx, taggedPosts...); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// ListTagUsage returns up to limit tags with the number of their threads, published posts, views and the activity
+// on their posts in the last days, in the sort order. Lists are cached for a short time, as counting the usage
+// walks all tagged posts.
+func (s *Service) ListTagUsage(ctx context.Context, sort string, limit, days int) ([]*apimodel.TagUsage, error) {
+	tagSort, err := model.ParseTagSort(sort)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if limit <= 0 || days <= 0 {
+		return nil, fmt.Errorf("%w: limit and days must be positive", ErrInvalidInput)
+	}
+
+	key := fmt.Sprintf("%s%s:%d:%d", tagUsageKeyPrefix, tagSort, limit, days)
+	if usage, ok := s.cachedTagUsage(ctx, key); ok {
+		return usage, nil
+	}
+
+	usage, err := s.listTagUsage(ctx, tagSort, limit, days)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheTagUsage(ctx, key, usage)
+	return usage, nil
+}
+
+// cachedTagUsage returns the cached list of tags. The cache is optional, failures are logged and treated as misses.
+func (s *Service) cachedTagUsage(ctx context.Context, key string) ([]*apimodel.TagUsage, bool) {
+	if s.tagUsageCacheTTL <= 0 {
+		return nil, false
+	}
+
+	data, err := s.redisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			s.log.WarnContext(ctx, "Failed to get cached tag usage", slog.Any("error", err))
+		}
+		return nil, false
+	}
+
+	var usage []*apimodel.TagUsage
+	if err = json.Unmarshal(data, &usage); err != nil {
+		s.log.WarnContext(ctx, "Failed to decode cached tag usage", slog.Any("error", err))
+		return nil, false
+	}
+	return usage, true
+}
+
+func (s *Service) cacheTagUsage(ctx context.Context, key string, usage []*apimodel.TagUsage) {
+	if s.tagUsageCacheTTL <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(usage)
+	if err != nil {
+		s.log.WarnContext(ctx, "Failed to encode tag usage", slog.Any("error", err))
+		return
+	}
+	if err = s.redisClient.Set(ctx, key, data, s.tagUsageCacheTTL).Err(); err != nil {
+		s.log.WarnContext(ctx, "Failed to cache tag usage", slog.Any("error", err))
+	}
+}
+
+func (s *Service) listTagUsage(
+	ctx context.Context,
+	tagSort model.TagSort,
+	limit, days int,
+) ([]*apimodel.TagUsage, error) {
+	usage, err := s.store.ListTagUsage(ctx, &model.TagUsageQuery{
+		Sort:  tagSort,
+		Since: time.Now().UTC().AddDate(0, 0, -days).Format(time.RFC3339),
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Weights are relative to the most used tag, which is not the first one when tags are sorted by name
+	weightOf := func(u *model.TagUsage) float64 { return float64(u.Posts) }
+	if tagSort == model.TagSortTrending {
+		weightOf = func(u *model.TagUsage) float64 { return u.Trend }
+	}
+	var top float64
+	for _, u := range usage {
+		top = max(top, weightOf(u))
+	}
+
+	resp := make([]*apimodel.TagUsage, len(usage))
+	for i, u := range usage {
+		resp[i] = &apimodel.TagUsage{
+			Name:           u.Tag.Name,
+			DisplayName:    u.Tag.DisplayName,
+			Threads:        u.Threads,
+			Posts:          u.Posts,
+			Views:          u.Views,
+			RecentPosts:    u.RecentPosts,
+			RecentComments: u.RecentComments,
+			RecentReads:    u.RecentReads,
+			Trend:          u.Trend,
+		}
+		if top > 0 {
+			resp[i].Weight = weightOf(u) / top
+		}
+	}
+
+	return resp, nil
+}
+
+// GetTag returns the tag with the name or alias.
+func (s *Service) GetTag(ctx context.Context, name string) (*apimodel.Tag, error) {
+	tag, err := s.getTag(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &apimodel.Tag{
+		Name:        tag.Name,
+		DisplayName: tag.DisplayName,
+		Parent:      tag.Parent,
+		Children:    []string{},
+		Aliases:     []string{},
+	}
+	resp.Children = append(resp.Children, tag.Children...)
+	resp.Aliases = append(resp.Aliases, tag.Aliases...)
+
+	return resp, nil
+}
+
+// UpdateTag changes the display name, parent or aliases of the tag with the name or alias, fields missing in data
+// are left as they are. It returns the name of the updated tag. It is allowed to admins.
+func (s *Service) UpdateTag(ctx context.Context, name string, data *apimodel.UpdateTagRequest) (string, error) {
+	if _, err := authorize(ctx, auth.ManageTags); err != nil {
+		return "", err
+	}
+
+	if data.DisplayName == nil && data.Parent == nil && len(data.AddAliases) == 0 && len(data.RemoveAliases) == 0 {
+		return "", fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+
+	tag, err := s.getTag(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	before := tagSummary(tag)
+	tag.ApplyUpdate(data)
+	if tag.DisplayName == "" {
+		tag.DisplayName = tag.Name
+	}
+	if tag.Parent == tag.Name {
+		return "", fmt.Errorf("%w: tag %s can't be its own parent", ErrInvalidInput, tag.Name)
+	}
+
+	if err = s.store.UpdateTag(ctx, tag); err != nil {
+		switch {
+		case errors.Is(err, posts.ErrNotFound):
+			return "", fmt.Errorf("%w: %v", ErrNotFound, err)
+		case errors.Is(err, posts.ErrConflict):
+			return "", fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return "", err
+	}
+
+	s.audit.Record(ctx, audit.TagUpdated, tag.Name, before, tagSummary(tag))
+	return tag.Name, nil
+}
+
+// MergeTags merges the tag named source into the tag named or aliased into. Posts and threads tagged with the source
+// are tagged with the target instead and the source becomes an alias of the target. It is allowed to admins.
+func (s *Service) MergeTags(ctx context.Context, source, into string) (*apimodel.TagMergeResponse, error) {
+	if _, err := authorize(ctx, auth.ManageTags); err != nil {
+		return nil, err
+	}
+
+	source, into = model.TagSlug(source), model.TagSlug(into)
+	switch {
+	case source == "" || into == "":
+		return nil, fmt.Errorf("%w: tags to merge are required", ErrInvalidInput)
+	case source == into:
+		return nil, fmt.Errorf("%w: tag %s can't be merged into itself", ErrInvalidInput, source)
+	}
+
+	merge, err := s.store.MergeTags(ctx, source, into)
+	if err != nil {
+		switch {
+		case errors.Is(err, posts.ErrNotFound):
+			return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+		case errors.Is(err, posts.ErrConflict):
+			return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return nil, err
+	}
+
+	s.audit.Record(
+		ctx,
+		audit.TagMerged,
+		merge.Source,
+		nil,
+		map[string]string{
+			"target":  merge.Target,
+			"posts":   strconv.Itoa(merge.Posts),
+			"threads": strconv.Itoa(merge.Threads),
+		},
+	)
+
+	return &apimodel.TagMergeResponse{
+		Source:  merge.Source,
+		Target:  merge.Target,
+		Posts:   merge.Posts,
+		Threads: merge.Threads,
+	}, nil
+}
+
+func (s *Service) getTag(ctx context.Context, name string) (*model.Tag, error) {
+	slug := model.TagSlug(name)
+	if slug == "" {
+		return nil, fmt.Errorf("%w: tag is empty", ErrInvalidInput)
+	}
+
+	tag, err := s.store.GetTag(ctx, slug)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error getting tag", slog.Any("error", err), slog.Any("tag", name))
+		return nil, err
+	}
+
+	return tag, nil
+}
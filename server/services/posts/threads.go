@@ -0,0 +1,116 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/audit"
+	"ndb/server/services/auth"
+)
+
+// UpdateThread renames, describes, retags or archives the thread, fields missing in data are left as they are.
+// It is allowed to editors and admins.
+func (s *Service) UpdateThread(ctx context.Context, threadID string, data *apimodel.UpdateThreadRequest) error {
+	if _, err := authorize(ctx, auth.ManageThreads); err != nil {
+		return err
+	}
+
+	data.Name = strings.TrimSpace(data.Name)
+	if data.Name == "" && data.Description == nil && data.Tags == nil && len(data.AddTags) == 0 &&
+		len(data.RemoveTags) == 0 && data.Archived == nil {
+		return fmt.Errorf("%w: nothing to update", ErrInvalidInput)
+	}
+
+	thread, err := s.store.GetThread(ctx, threadID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		s.log.ErrorContext(ctx, "Error getting thread", slog.Any("error", err), slog.Any("thread_id", threadID))
+		return err
+	}
+
+	before := threadSummary(thread)
+	thread.ApplyUpdate(data)
+	if err = s.store.UpdateThread(ctx, thread); err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		return err
+	}
+
+	s.audit.Record(ctx, audit.ThreadUpdated, threadID, before, threadSummary(thread))
+	return nil
+}
+
+// DeleteThread deletes the thread, its posts are handled according to the policy. Moving posts requires the ID
+// of the thread they are moved to. It returns the deletion date and the number of moved posts.
+// It is allowed to editors and admins.
+func (s *Service) DeleteThread(
+	ctx context.Context,
+	threadID, policy, targetID string,
+) (*apimodel.ThreadDeletionResponse, error) {
+	if _, err := authorize(ctx, auth.ManageThreads); err != nil {
+		return nil, err
+	}
+
+	deletePolicy, err := model.ParseThreadDeletePolicy(policy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	switch {
+	case deletePolicy == model.DeleteMove && targetID == "":
+		return nil, fmt.Errorf("%w: target thread is required to move posts", ErrInvalidInput)
+	case deletePolicy == model.DeleteMove && targetID == threadID:
+		return nil, fmt.Errorf("%w: posts can't be moved to the deleted thread", ErrInvalidInput)
+	case deletePolicy != model.DeleteMove && targetID != "":
+		return nil, fmt.Errorf("%w: target thread is only used by the move policy", ErrInvalidInput)
+	}
+
+	deletedAt := time.Now().UTC().Format(time.RFC3339)
+	threadPosts, err := s.store.DeleteThread(ctx, threadID, deletePolicy, targetID, deletedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, posts.ErrNotFound):
+			return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+		case errors.Is(err, posts.ErrConflict):
+			return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return nil, err
+	}
+
+	for _, post := range threadPosts {
+		if deletePolicy == model.DeleteCascade {
+			s.audit.Record(ctx, audit.PostDeleted, post.PostID, postSummary(post), map[string]string{"deleted_at": deletedAt})
+		} else {
+			s.audit.Record(
+				ctx,
+				audit.PostUpdated,
+				post.PostID,
+				map[string]string{"thread_id": threadID},
+				map[string]string{"thread_id": targetID},
+			)
+		}
+	}
+	s.audit.Record(
+		ctx,
+		audit.ThreadDeleted,
+		threadID,
+		nil,
+		map[string]string{"deleted_at": deletedAt, "policy": string(deletePolicy), "target_id": targetID},
+	)
+
+	return &apimodel.ThreadDeletionResponse{
+		ThreadID:  threadID,
+		Policy:    string(deletePolicy),
+		DeletedAt: deletedAt,
+		Posts:     len(threadPosts),
+	}, nil
+}
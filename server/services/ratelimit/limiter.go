@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const keyPrefix = "ratelimit:"
+
+// slidingWindow keeps timestamps of requests in the window in a sorted set and admits a request while there
+// are fewer than the limit of them. It returns whether the request is admitted, the number of requests left
+// and the milliseconds until the oldest request leaves the window.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+    redis.call('ZADD', key, now, ARGV[4])
+    redis.call('PEXPIRE', key, window)
+    count = count + 1
+    allowed = 1
+end
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local reset = window
+if oldest[2] then
+    reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, limit - count, reset}
+`)
+
+// Result describes the state of a limit after a request was counted against it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until another request fits into the window
+	Reset time.Duration
+}
+
+// Limiter counts requests in a sliding window shared by all instances of the server through redis.
+type Limiter struct {
+	redisClient *redis.Client
+	log         *slog.Logger
+	window      time.Duration
+}
+
+func NewLimiter(redisClient *redis.Client, window time.Duration, log *slog.Logger) *Limiter {
+	return &Limiter{
+		redisClient: redisClient,
+		log:         log,
+		window:      window,
+	}
+}
+
+// Window returns the length of the window limits are counted in.
+func (l *Limiter) Window() time.Duration {
+	return l.window
+}
+
+// Allow counts the request against the limit of the key, requests over the limit are not counted.
+func (l *Limiter) Allow(ctx context.Context, key string, limit int) (*Result, error) {
+	now := time.Now().UnixMilli()
+
+	values, err := slidingWindow.Run(
+		ctx,
+		l.redisClient,
+		[]string{keyPrefix + key},
+		now,
+		l.window.Milliseconds(),
+		limit,
+		strconv.FormatInt(now, 10)+"-"+uuid.New().String(),
+	).Int64Slice()
+	if err != nil {
+		l.log.ErrorContext(ctx, "Failed to check rate limit", slog.Any("error", err), slog.Any("key", key))
+		return nil, fmt.Errorf("failed to check rate limit: %v", err)
+	}
+
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", values)
+	}
+
+	return &Result{
+		Allowed:   values[0] == 1,
+		Limit:     limit,
+		Remaining: int(values[1]),
+		Reset:     time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,349 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	apimodel "ndb/server/app/models"
+	"ndb/server/config"
+	"ndb/server/repositories/posts"
+	"ndb/server/repositories/posts/model"
+	"ndb/server/services/auth"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything past the first 72 bytes of the password
+	maxPasswordLength = 72
+	maxBioLength      = 1000
+	maxAvatarSize     = 2 << 20 // 2MB
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+// avatarTypes maps accepted avatar content types to file extensions
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type FileService interface {
+	InsertFile(
+		ctx context.Context,
+		fileName string,
+		file []byte,
+	) error
+	GetFile(
+		ctx context.Context,
+		fileName string,
+	) (io.ReadCloser, error)
+	DeleteFile(
+		ctx context.Context,
+		fileName string,
+	) error
+}
+
+type Service struct {
+	store       *posts.Store
+	log         *slog.Logger
+	fileManager FileService
+	defaultRole model.Role
+	admins      []string
+	// registration is closed when users sign in through OIDC only
+	passwordLogin bool
+}
+
+func NewService(fileManager FileService, store *posts.Store, cfg *config.Auth, log *slog.Logger) (*Service, error) {
+	defaultRole, ok := model.ParseRole(cfg.DefaultRole)
+	if !ok {
+		return nil, fmt.Errorf("unknown default role: %s", cfg.DefaultRole)
+	}
+
+	admins := make([]string, len(cfg.Admins))
+	for i, username := range cfg.Admins {
+		admins[i] = strings.ToLower(strings.TrimSpace(username))
+	}
+
+	return &Service{
+		fileManager:   fileManager,
+		store:         store,
+		log:           log,
+		defaultRole:   defaultRole,
+		admins:        admins,
+		passwordLogin: cfg.PasswordLogin,
+	}, nil
+}
+
+// Register creates a user with a bcrypt hash of the password. Users get the default role, unless their username
+// is configured as an admin one. It is forbidden when password login is disabled.
+func (s *Service) Register(ctx context.Context, data *apimodel.RegisterUserRequest) (string, error) {
+	if !s.passwordLogin {
+		return "", fmt.Errorf("%w: registration with a password is disabled, sign in with OIDC", ErrForbidden)
+	}
+
+	if !usernamePattern.MatchString(data.Username) {
+		return "", fmt.Errorf("%w: username must have 3 to 32 letters, digits, '_' or '-'", ErrInvalidInput)
+	}
+
+	if _, err := mail.ParseAddress(data.Email); err != nil {
+		return "", fmt.Errorf("%w: invalid email: %v", ErrInvalidInput, err)
+	}
+
+	if len(data.Password) < minPasswordLength || len(data.Password) > maxPasswordLength {
+		return "", fmt.Errorf(
+			"%w: password must have %d to %d bytes",
+			ErrInvalidInput,
+			minPasswordLength,
+			maxPasswordLength,
+		)
+	}
+
+	if len(data.Bio) > maxBioLength {
+		return "", fmt.Errorf("%w: bio is longer than %d bytes", ErrInvalidInput, maxBioLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(data.Password), bcrypt.DefaultCost)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error hashing password", slog.Any("error", err))
+		return "", err
+	}
+
+	role := s.defaultRole
+	if slices.Contains(s.admins, strings.ToLower(data.Username)) {
+		role = model.RoleAdmin
+	}
+
+	userID, err := s.store.CreateUser(ctx, model.UserFrom(data, string(hash), role))
+	if err != nil {
+		if errors.Is(err, posts.ErrConflict) {
+			return "", fmt.Errorf("%w: username or email is already taken", ErrConflict)
+		}
+		s.log.ErrorContext(ctx, "Error creating user", slog.Any("error", err))
+		return "", err
+	}
+
+	return userID, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, userID string) (*apimodel.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAPIUser(user), nil
+}
+
+// GetProfile returns the user with statistics of their published posts. View counts buffered since the last
+// flush are not included.
+func (s *Service) GetProfile(ctx context.Context, userID string) (*apimodel.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.store.GetUserStats(ctx, userID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: user %s", ErrNotFound, userID)
+		}
+		return nil, err
+	}
+
+	profile := toAPIUser(user)
+	profile.Stats = &apimodel.UserStats{
+		PostCount:  stats.PostCount,
+		TotalViews: stats.TotalViews,
+		Threads:    make([]*apimodel.ThreadContribution, len(stats.Threads)),
+	}
+	for i, thread := range stats.Threads {
+		profile.Stats.Threads[i] = &apimodel.ThreadContribution{
+			ThreadID:  thread.ThreadID,
+			Name:      thread.Name,
+			PostCount: thread.PostCount,
+		}
+	}
+
+	return profile, nil
+}
+
+// UpdateProfile replaces the profile fields present in data. Users change their own profiles, admins any profile.
+func (s *Service) UpdateProfile(ctx context.Context, userID string, data *apimodel.UpdateUserRequest) error {
+	if err := authorizeProfile(ctx, userID); err != nil {
+		return err
+	}
+
+	if len(data.Bio) > maxBioLength {
+		return fmt.Errorf("%w: bio is longer than %d bytes", ErrInvalidInput, maxBioLength)
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.ApplyUpdate(data)
+	return s.updateUser(ctx, user)
+}
+
+// UploadAvatar stores the image in S3 and makes it the avatar of the user, replacing the previous one.
+func (s *Service) UploadAvatar(ctx context.Context, userID string, file io.Reader) (string, error) {
+	if err := authorizeProfile(ctx, userID); err != nil {
+		return "", err
+	}
+
+	content, err := io.ReadAll(io.LimitReader(file, maxAvatarSize+1))
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading avatar", slog.Any("error", err))
+		return "", err
+	}
+
+	if len(content) > maxAvatarSize {
+		return "", fmt.Errorf("%w: avatar is larger than %d bytes", ErrInvalidInput, maxAvatarSize)
+	}
+
+	extension, ok := avatarTypes[http.DetectContentType(content)]
+	if !ok {
+		return "", fmt.Errorf("%w: avatar must be a png, jpeg, gif or webp image", ErrInvalidInput)
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	previous := user.AvatarKey
+	user.AvatarKey = fmt.Sprintf("avatars/%s%s", uuid.New().String(), extension)
+	if err = s.fileManager.InsertFile(ctx, user.AvatarKey, content); err != nil {
+		s.log.ErrorContext(ctx, "Error inserting avatar", slog.Any("error", err), slog.Any("user_id", userID))
+		return "", err
+	}
+
+	user.ApplyUpdate(&apimodel.UpdateUserRequest{})
+	if err = s.updateUser(ctx, user); err != nil {
+		return "", err
+	}
+
+	if previous != "" {
+		if err = s.fileManager.DeleteFile(ctx, previous); err != nil {
+			s.log.ErrorContext(ctx, "Error deleting previous avatar", slog.Any("error", err), slog.Any("user_id", userID))
+		}
+	}
+
+	return user.AvatarKey, nil
+}
+
+// SetRole changes the role of the user, it is allowed to admins only. The new role applies to access tokens
+// issued after the change.
+func (s *Service) SetRole(ctx context.Context, userID, name string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || !principal.Can(auth.ManageUsers) {
+		return fmt.Errorf("%w: only admins can change roles", ErrForbidden)
+	}
+
+	role, ok := model.ParseRole(name)
+	if !ok {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidInput, name)
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Role = role
+	return s.updateUser(ctx, user)
+}
+
+// GetAvatar returns the avatar image of the user together with its content type.
+func (s *Service) GetAvatar(ctx context.Context, userID string) ([]byte, string, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if user.AvatarKey == "" {
+		return nil, "", fmt.Errorf("%w: user %s has no avatar", ErrNotFound, userID)
+	}
+
+	rc, err := s.fileManager.GetFile(ctx, user.AvatarKey)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		s.log.ErrorContext(ctx, "Error reading avatar", slog.Any("error", err), slog.Any("user_id", userID))
+		return nil, "", err
+	}
+
+	return content, http.DetectContentType(content), nil
+}
+
+func (s *Service) getUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.store.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return nil, fmt.Errorf("%w: user %s", ErrNotFound, userID)
+		}
+		s.log.ErrorContext(ctx, "Error getting user", slog.Any("error", err), slog.Any("user_id", userID))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) updateUser(ctx context.Context, user *model.User) error {
+	err := s.store.UpdateUser(ctx, user)
+	if err != nil {
+		if errors.Is(err, posts.ErrNotFound) {
+			return fmt.Errorf("%w: user %s", ErrNotFound, user.UserID)
+		}
+		s.log.ErrorContext(ctx, "Error updating user", slog.Any("error", err), slog.Any("user_id", user.UserID))
+		return err
+	}
+
+	return nil
+}
+
+// authorizeProfile checks that the principal of the request is the user or an admin.
+func authorizeProfile(ctx context.Context, userID string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || !(principal.UserID == userID && principal.Can(auth.EditProfile) || principal.Can(auth.ManageUsers)) {
+		return fmt.Errorf("%w: can't change profile of user %s", ErrForbidden, userID)
+	}
+	return nil
+}
+
+func toAPIUser(user *model.User) *apimodel.User {
+	return &apimodel.User{
+		UserID:      user.UserID,
+		Username:    user.Username,
+		Role:        string(user.Role),
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarKey:   user.AvatarKey,
+		CreatedAt:   user.CreatedAt,
+	}
+}
@@ -0,0 +1,177 @@
+package views
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"ndb/server/config"
+)
+
+const (
+	pendingKeyPrefix = "views:pending:"
+	seenKeyPrefix    = "views:seen:"
+	dirtyKey         = "views:dirty"
+)
+
+type Store interface {
+	IncrementViewCounts(ctx context.Context, counts map[string]int64) error
+}
+
+// Service buffers post views in redis and periodically flushes them to the store.
+type Service struct {
+	redisClient *redis.Client
+	store       Store
+	log         *slog.Logger
+	dedupWindow time.Duration
+}
+
+func NewService(redisClient *redis.Client, store Store, cfg *config.Views, log *slog.Logger) *Service {
+	return &Service{
+		redisClient: redisClient,
+		store:       store,
+		log:         log,
+		dedupWindow: cfg.DedupWindow,
+	}
+}
+
+// Record counts a view of the post, unless the same visitor has already viewed it within the deduplication window.
+func (s *Service) Record(ctx context.Context, postID, visitorID string) error {
+	hash := sha256.Sum256([]byte(visitorID))
+	seenKey := fmt.Sprintf("%s%s:%s", seenKeyPrefix, postID, hex.EncodeToString(hash[:]))
+
+	firstView, err := s.redisClient.SetNX(ctx, seenKey, 1, s.dedupWindow).Result()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to check visitor", slog.Any("error", err), slog.Any("post_id", postID))
+		return fmt.Errorf("failed to check visitor: %v", err)
+	}
+
+	if !firstView {
+		return nil
+	}
+
+	if err = s.redisClient.Incr(ctx, pendingKeyPrefix+postID).Err(); err != nil {
+		s.log.ErrorContext(ctx, "Failed to increment views", slog.Any("error", err), slog.Any("post_id", postID))
+		return fmt.Errorf("failed to increment views: %v", err)
+	}
+
+	if err = s.redisClient.SAdd(ctx, dirtyKey, postID).Err(); err != nil {
+		s.log.ErrorContext(ctx, "Failed to mark views to flush", slog.Any("error", err), slog.Any("post_id", postID))
+		return fmt.Errorf("failed to mark views to flush: %v", err)
+	}
+
+	return nil
+}
+
+// Pending returns the views of the posts which are not flushed to the store yet.
+func (s *Service) Pending(ctx context.Context, postIDs ...string) (map[string]int64, error) {
+	pending := make(map[string]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return pending, nil
+	}
+
+	keys := make([]string, len(postIDs))
+	for i, postID := range postIDs {
+		keys[i] = pendingKeyPrefix + postID
+	}
+
+	values, err := s.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		s.log.ErrorContext(ctx, "Failed to get pending views", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get pending views: %v", err)
+	}
+
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		count, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			continue
+		}
+		pending[postIDs[i]] = count
+	}
+
+	return pending, nil
+}
+
+// Flush moves the buffered views to the store. Posts stay marked until their counts are persisted, counts which
+// could not be persisted are put back to the buffer, so the next flush retries them.
+func (s *Service) Flush(ctx context.Context) (int, error) {
+	postIDs, err := s.redisClient.SMembers(ctx, dirtyKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list posts with pending views: %v", err)
+	}
+
+	counts := make(map[string]int64, len(postIDs))
+	for _, postID := range postIDs {
+		count, err := s.redisClient.GetDel(ctx, pendingKeyPrefix+postID).Int64()
+		if errors.Is(err, redis.Nil) {
+			s.unmark(ctx, postID)
+			continue
+		}
+		if err != nil {
+			s.log.ErrorContext(ctx, "Failed to take pending views", slog.Any("error", err), slog.Any("post_id", postID))
+			continue
+		}
+
+		counts[postID] = count
+	}
+
+	if len(counts) == 0 {
+		return 0, nil
+	}
+
+	if err = s.store.IncrementViewCounts(ctx, counts); err != nil {
+		s.restore(ctx, counts)
+		return 0, err
+	}
+
+	for postID := range counts {
+		s.unmark(ctx, postID)
+	}
+
+	return len(counts), nil
+}
+
+// unmark removes the post from the posts with pending views. Views recorded after its counter was taken mark
+// the post again, so it is marked back when the counter exists again or can't be checked.
+func (s *Service) unmark(ctx context.Context, postID string) {
+	if err := s.redisClient.SRem(ctx, dirtyKey, postID).Err(); err != nil {
+		s.log.ErrorContext(ctx, "Failed to unmark views to flush", slog.Any("error", err), slog.Any("post_id", postID))
+		return
+	}
+
+	pending, err := s.redisClient.Exists(ctx, pendingKeyPrefix+postID).Result()
+	if err != nil || pending > 0 {
+		if err = s.redisClient.SAdd(ctx, dirtyKey, postID).Err(); err != nil {
+			s.log.ErrorContext(ctx, "Failed to mark views to flush", slog.Any("error", err), slog.Any("post_id", postID))
+		}
+	}
+}
+
+func (s *Service) restore(ctx context.Context, counts map[string]int64) {
+	for postID, count := range counts {
+		err := s.redisClient.IncrBy(ctx, pendingKeyPrefix+postID, count).Err()
+		if err == nil {
+			err = s.redisClient.SAdd(ctx, dirtyKey, postID).Err()
+		}
+		if err != nil {
+			s.log.ErrorContext(
+				ctx,
+				"Failed to restore pending views, views are lost",
+				slog.Any("error", err),
+				slog.Any("post_id", postID),
+				slog.Int64("views", count),
+			)
+		}
+	}
+}